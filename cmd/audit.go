@@ -10,14 +10,66 @@ import (
 	"github.com/briandowns/spinner"
 	"github.com/rollwagen/s3-cisbench/internal/audit"
 	"github.com/rollwagen/s3-cisbench/internal/aws"
+	"github.com/rollwagen/s3-cisbench/internal/awsconfig"
+	"github.com/rollwagen/s3-cisbench/internal/history"
 	"github.com/rollwagen/s3-cisbench/internal/printers"
+	"github.com/rollwagen/s3-cisbench/internal/remediation"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
-var outputFormat string
+var (
+	outputFormat string
+	outputFile   string
+	emitFormat   string
+
+	includeBuckets []string
+	excludeBuckets []string
+	includeTags    []string
+	excludeTags    []string
+
+	objectLockBuckets []string
+	objectLockTags    []string
+	objectLockMode    string
+	objectLockMinDays int32
+
+	replicationBuckets            []string
+	replicationTags               []string
+	replicationCrossRegion        bool
+	replicationCrossAccount       bool
+	replicationDestinationAccount string
+	replicationKMS                bool
+	replicationDeleteMarkers      bool
+
+	requiredTags     []string
+	allowedTagValues []string
+
+	profilesFile string
+	profiles     []audit.Profile
+
+	suppressionsFile string
+	suppressions     []audit.Suppression
+	baselineFile     string
+	baselineDays     int
+
+	historyFile string
+
+	rulesDir string
+	rules    []audit.Rule
+
+	benchmark string
+	framework string
+
+	regions        []string
+	excludeRegions []string
+
+	configSnapshots []string
+	stateFile       string
+	dumpStateFile   string
+)
 
 func getBucketsCompletion(toComplete string) []string {
+	configureEndpoint() // PersistentPreRunE does not run for shell completion
 	completions, err := aws.GetBucketNamesWithPrefix(toComplete)
 	if err != nil {
 		log.Debugf("Could not complete names %v", err)
@@ -38,64 +90,433 @@ var auditCmd = &cobra.Command{
 
 		return getBucketsCompletion(toComplete), cobra.ShellCompDirectiveNoFileComp
 	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if stateFile != "" && len(configSnapshots) != 0 {
+			return fmt.Errorf("--from-state and --from-config-snapshot are mutually exclusive")
+		}
+		return validateAuditFlags(cmd, args)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
+		switch {
+		case stateFile != "":
+			auditStateFile(args)
+			return
+		case len(configSnapshots) != 0:
+			auditConfigSnapshots(args)
+			return
+		}
+
 		const duration = 60 * time.Millisecond
 		spinner := spinner.New(spinner.CharSets[11], duration)
 		if !debug { // no spinner when debug output enabled
 			spinner.Start()
 		}
 
-		var buckets []aws.Bucket
-		if len(args) != 0 {
-			name := args[0]
-			bucket, err := aws.GetBucketByName(name)
-			if err != nil {
-				log.Errorf("Error S3 bucket with name %s: %v", name, err)
-			}
-			buckets = append(buckets, bucket)
-		} else {
-			spinner.Suffix = " Getting S3 buckets..."
-			var err error
-			buckets, err = aws.GetBuckets()
-			if err != nil {
-				spinner.Stop()
-				var e smithy.APIError
-				if errors.As(err, &e) {
-					log.Errorf("Error listing S3 buckets: %v: %v", e.ErrorCode(), e.ErrorMessage())
-				} else {
-					log.Errorf("Unexpected error: %v", err)
-				}
-				os.Exit(1)
-			}
-		}
+		spinner.Suffix = " Getting S3 buckets..."
+		buckets := getBuckets(args, spinner.Stop)
 
 		spinner.Suffix = " Auditing buckets..."
-		bucketAuditor := audit.New()
-		var reports []audit.BucketReport
+		withACL := len(rules) != 0 || dumpStateFile != "" // a state file may be evaluated against rules later
+		var states []audit.BucketState
 		for i, b := range buckets {
 			spinner.Suffix = fmt.Sprintf(" Auditing buckets: [%d/%d] %spinner...", i, len(buckets), b.Name)
-			reports = append(reports, bucketAuditor.Report(b.Name, b.AccountID, b.Region))
+			states = append(states, audit.Collect(b, withACL))
 		}
 		spinner.Suffix = " Printing report..."
 		spinner.Stop()
 
-		writer := os.Stdout
-		var printer printers.BucketReportPrinter
-		switch {
-		case outputFormat == "txt":
-			printer = &printers.TextPrinter{}
-		case outputFormat == "json":
-			printer = &printers.JSONPrinter{}
-		case outputFormat == "csv":
-			printer = &printers.CSVPrinter{}
-		case outputFormat == "noout":
-			printer = &printers.NooutPrinter{}
-		}
-		_ = printer.PrintReport(reports, writer)
+		printReportsAt(evaluateStates(states), time.Now().UTC(), true)
 	},
 }
 
+// getBuckets returns the bucket given as argument or else all buckets, with the bucket filters applied;
+// if listing fails, stop is called before exiting.
+func getBuckets(args []string, stop func()) []aws.Bucket {
+	var buckets []aws.Bucket
+	if len(args) != 0 {
+		name := args[0]
+		bucket, err := aws.GetBucketByName(name)
+		if err != nil {
+			log.Errorf("Error S3 bucket with name %s: %v", name, err)
+		}
+		buckets = append(buckets, bucket)
+	} else {
+		var err error
+		buckets, err = aws.GetBuckets(regionFilter())
+		if err != nil {
+			stop()
+			var e smithy.APIError
+			if errors.As(err, &e) {
+				log.Errorf("Error listing S3 buckets: %v: %v", e.ErrorCode(), e.ErrorMessage())
+			} else {
+				log.Errorf("Unexpected error: %v", err)
+			}
+			os.Exit(1)
+		}
+	}
+
+	return filterBuckets(buckets)
+}
+
+// auditConfigSnapshots audits the buckets recorded in AWS Config snapshot files without calling AWS.
+func auditConfigSnapshots(args []string) {
+	collected, err := awsconfig.LoadBuckets(configSnapshots)
+	if err != nil {
+		log.Errorf("Error reading AWS Config snapshot: %v", err)
+		os.Exit(1)
+	}
+
+	var name string
+	if len(args) != 0 {
+		name = args[0]
+	}
+	reports := evaluateReports(collected, name)
+	if name != "" && len(reports) == 0 {
+		log.Errorf("Bucket %s not found in AWS Config snapshot", name)
+		os.Exit(1)
+	}
+
+	printReports(reports)
+}
+
+// auditStateFile evaluates the controls against the bucket states of a file written with --dump-state.
+func auditStateFile(args []string) {
+	file, err := audit.LoadStates(stateFile)
+	if err != nil {
+		log.Errorf("Error reading state file: %v", err)
+		os.Exit(1)
+	}
+	log.Infof("Evaluating %d bucket(s) collected at %s", len(file.Buckets), file.CollectedAt.Format(time.RFC3339))
+
+	var name string
+	if len(args) != 0 {
+		name = args[0]
+	}
+	reports := evaluateReports(file.Buckets, name)
+	if name != "" && len(reports) == 0 {
+		log.Errorf("Bucket %s not found in state file", name)
+		os.Exit(1)
+	}
+
+	printReportsAt(reports, file.CollectedAt, false)
+}
+
+// evaluateReports applies the bucket filters to states collected offline and evaluates the controls;
+// if name is set, only the bucket with this name is evaluated.
+func evaluateReports(collected []audit.BucketState, name string) []audit.BucketReport {
+	inRegions := regionFilter()
+	filter := bucketFilter()
+	var states []audit.BucketState
+	for _, state := range collected {
+		switch {
+		case name != "" && state.Name != name:
+			continue
+		case !inRegions.Matches(state.Region):
+			log.Debugf("Bucket %s in region %s excluded by region filter", state.Name, state.Region)
+			continue
+		case !filter.Matches(state.Name, state.Tags):
+			log.Debugf("Bucket %s excluded by filter", state.Name)
+			continue
+		}
+		states = append(states, state)
+	}
+	return evaluateStates(states)
+}
+
+// evaluateStates writes the bucket states to the --dump-state file, if set, and evaluates the controls.
+func evaluateStates(states []audit.BucketState) []audit.BucketReport {
+	if dumpStateFile != "" {
+		if err := audit.WriteStates(dumpStateFile, states, time.Now().UTC()); err != nil {
+			log.Errorf("Error writing state file: %v", err)
+			os.Exit(1)
+		}
+		log.Infof("Wrote state of %d bucket(s) to %s", len(states), dumpStateFile)
+	}
+
+	bucketAuditor := audit.New(auditOptions()...)
+	var reports []audit.BucketReport
+	for _, state := range states {
+		reports = append(reports, bucketAuditor.Evaluate(state))
+	}
+	return reports
+}
+
+// printReports prints the reports of bucket states not read from AWS, e.g. of templates.
+func printReports(reports []audit.BucketReport) {
+	printReportsAt(reports, time.Now().UTC(), false)
+}
+
+// printReportsAt prints the reports of bucket states collected at the given time, which is the time of the
+// run recorded in the history; live is set if the states were read from AWS by this run.
+func printReportsAt(reports []audit.BucketReport, collectedAt time.Time, live bool) {
+	if historyFile != "" {
+		recordHistory(reports, collectedAt)
+	}
+	if baselineFile != "" {
+		writeBaseline(reports)
+	}
+	if emitFormat != "" {
+		emitRemediation(reports, live)
+		return
+	}
+
+	writer := os.Stdout
+	if outputFile != "" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			log.Errorf("Error writing report: %v", err)
+			os.Exit(1)
+		}
+		defer func() { _ = f.Close() }()
+		writer = f
+	}
+	var printer printers.BucketReportPrinter
+	switch {
+	case outputFormat == "txt":
+		printer = &printers.TextPrinter{Framework: audit.Framework(framework)}
+	case outputFormat == "json":
+		printer = &printers.JSONPrinter{}
+	case outputFormat == "csv":
+		printer = &printers.CSVPrinter{}
+	case outputFormat == "noout":
+		printer = &printers.NooutPrinter{}
+	}
+	_ = printer.PrintReport(reports, writer)
+}
+
+// recordHistory records the reports as audit run at the time the bucket states were collected.
+func recordHistory(reports []audit.BucketReport, collectedAt time.Time) {
+	run := history.Run{Time: collectedAt.UTC(), Reports: reports}
+	if len(configSnapshots) == 0 && stateFile == "" {
+		identity, err := aws.GetCallerIdentity()
+		if err != nil {
+			log.Warnf("Could not get caller identity for history: %v", err)
+		}
+		run.Identity = identity
+	}
+
+	store, err := history.Open(historyFile, false)
+	if err != nil {
+		log.Errorf("Error recording history: %v", err)
+		os.Exit(1)
+	}
+	defer func() { _ = store.Close() }()
+	if err := store.Record(&run); err != nil {
+		log.Errorf("Error recording history: %v", err)
+		os.Exit(1)
+	}
+	log.Infof("Recorded run %d in history %s", run.ID, historyFile)
+}
+
+// writeBaseline writes a suppression for every failed finding of the reports to the baseline file.
+func writeBaseline(reports []audit.BucketReport) {
+	now := time.Now()
+	justification := "Baseline of " + now.Format(audit.SuppressionDateFormat) + "; to be reviewed"
+	baseline := audit.Baseline(reports, justification, now.AddDate(0, 0, baselineDays))
+	if err := audit.WriteSuppressions(baselineFile, baseline); err != nil {
+		log.Errorf("Error writing baseline: %v", err)
+		os.Exit(1)
+	}
+	log.Infof("Wrote %d suppression(s) to baseline %s", len(baseline), baselineFile)
+}
+
+// validateAuditFlags validates the flags added by addAuditFlags and loads the profiles.
+func validateAuditFlags(_ *cobra.Command, _ []string) error {
+	if err := audit.ValidateObjectLockMode(objectLockMode); err != nil {
+		return err
+	}
+	if err := audit.ValidateBenchmark(audit.Benchmark(benchmark)); err != nil {
+		return err
+	}
+	if err := audit.ValidateFramework(audit.Framework(framework)); err != nil {
+		return err
+	}
+	for _, patterns := range [][]string{includeBuckets, excludeBuckets, objectLockBuckets, replicationBuckets} {
+		if err := audit.ValidatePatterns(patterns); err != nil {
+			return err
+		}
+	}
+	for _, tags := range [][]string{includeTags, excludeTags, objectLockTags, replicationTags} {
+		if _, err := audit.ParseTagFilters(tags); err != nil {
+			return err
+		}
+	}
+	if _, err := audit.ParseAllowedTagValues(allowedTagValues); err != nil {
+		return err
+	}
+	if emitFormat != "" {
+		if _, err := remediation.NewEmitter(emitFormat); err != nil {
+			return err
+		}
+	}
+	profiles, suppressions = configProfiles, configSuppressions
+	if profilesFile != "" {
+		loaded, err := audit.LoadProfiles(profilesFile)
+		if err != nil {
+			return err
+		}
+		profiles = append(loaded, profiles...)
+	}
+	if suppressionsFile != "" {
+		loaded, err := audit.LoadSuppressions(suppressionsFile)
+		if err != nil {
+			return err
+		}
+		suppressions = append(loaded, suppressions...)
+	}
+	if rulesDir != "" {
+		loaded, err := audit.LoadRules(rulesDir)
+		if err != nil {
+			return err
+		}
+		rules = loaded
+	}
+	if outputFile != "" && outputFormat != "csv" && outputFormat != "json" {
+		return fmt.Errorf("--output-file requires output format csv or json")
+	}
+	return nil
+}
+
+// addRegionFlags adds the region filter flags to a command listing buckets.
+func addRegionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&regions, "regions", nil, "Only include buckets in these regions, e.g. 'eu-central-1,eu-west-1'")
+	cmd.Flags().StringSliceVar(&excludeRegions, "exclude-regions", nil, "Do not include buckets in these regions")
+}
+
+func regionFilter() aws.RegionFilter {
+	return aws.RegionFilter{Regions: regions, ExcludeRegions: excludeRegions}
+}
+
+func bucketFilter() audit.BucketFilter {
+	tags, _ := audit.ParseTagFilters(includeTags) // validated in PreRunE
+	notTags, _ := audit.ParseTagFilters(excludeTags)
+	return audit.BucketFilter{Include: includeBuckets, Exclude: excludeBuckets, Tags: tags, ExcludeTags: notTags}
+}
+
+// filterBuckets applies the region, include/exclude name and tag filters to the buckets.
+func filterBuckets(buckets []aws.Bucket) []aws.Bucket {
+	inRegions := regionFilter()
+	filter := bucketFilter()
+
+	var filtered []aws.Bucket
+	for _, b := range buckets {
+		if !inRegions.Matches(b.Region) {
+			log.Debugf("Bucket %s in region %s excluded by region filter", b.Name, b.Region)
+			continue
+		}
+		if filter.NeedsTags() { // the tags are passed on to the audit instead of getting them again
+			s3Client, err := aws.NewS3Client(b.Region)
+			if err == nil {
+				b.Tags, err = aws.GetBucketTags(s3Client, b)
+			}
+			if err != nil {
+				log.Warnf("Skipping bucket %s, could not get tags: %v", b.Name, err)
+				continue
+			}
+		}
+		if filter.Matches(b.Name, b.Tags) {
+			filtered = append(filtered, b)
+		} else {
+			log.Debugf("Bucket %s excluded by filter", b.Name)
+		}
+	}
+	return filtered
+}
+
+// auditOptions converts the command line flags into bucket auditor options.
+func auditOptions() []audit.Option {
+	var opts []audit.Option
+
+	if len(objectLockBuckets) != 0 || len(objectLockTags) != 0 {
+		tags, _ := audit.ParseTagFilters(objectLockTags) // validated in PreRunE
+		opts = append(opts, audit.WithObjectLockRequirement(audit.ObjectLockRequirement{
+			Selector:         audit.BucketSelector{Names: objectLockBuckets, Tags: tags},
+			Mode:             objectLockMode,
+			MinRetentionDays: objectLockMinDays,
+		}))
+	}
+
+	if len(replicationBuckets) != 0 || len(replicationTags) != 0 {
+		tags, _ := audit.ParseTagFilters(replicationTags) // validated in PreRunE
+		opts = append(opts, audit.WithReplicationRequirement(audit.ReplicationRequirement{
+			Selector:                audit.BucketSelector{Names: replicationBuckets, Tags: tags},
+			CrossRegion:             replicationCrossRegion,
+			CrossAccount:            replicationCrossAccount,
+			DestinationAccount:      replicationDestinationAccount,
+			ReplicaKMS:              replicationKMS,
+			DeleteMarkerReplication: replicationDeleteMarkers,
+		}))
+	}
+
+	allowed, _ := audit.ParseAllowedTagValues(allowedTagValues) // validated in PreRunE
+	tagPolicy := audit.TagPolicy{Required: requiredTags, AllowedValues: allowed}
+	if !tagPolicy.IsEmpty() {
+		opts = append(opts, audit.WithTagPolicy(tagPolicy))
+	}
+
+	if len(profiles) != 0 {
+		opts = append(opts, audit.WithProfiles(profiles))
+	}
+
+	if len(suppressions) != 0 {
+		opts = append(opts, audit.WithSuppressions(suppressions))
+	}
+	if benchmark != "" {
+		opts = append(opts, audit.WithBenchmark(audit.Benchmark(benchmark)))
+	}
+	if framework != "" {
+		opts = append(opts, audit.WithFramework(audit.Framework(framework)))
+	}
+	if len(rules) != 0 {
+		opts = append(opts, audit.WithRules(rules))
+	}
+	if len(enabledControls) != 0 {
+		opts = append(opts, audit.WithControls(enabledControls))
+	}
+	if len(severityOverrides) != 0 {
+		opts = append(opts, audit.WithSeverities(severityOverrides))
+	}
+
+	return opts
+}
+
+// addAuditFlags adds the output, bucket filter and control flags shared by the commands auditing buckets.
+func addAuditFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "txt", "Define outputFormat report (txt, csv, json, noout)")
+	cmd.Flags().StringVar(&outputFile, "output-file", "", "Write csv and json reports to this file instead of stdout")
+	cmd.Flags().StringVar(&emitFormat, "emit", "", "Instead of the report, emit remediation of failed controls (terraform, cloudformation, cli)")
+	cmd.Flags().StringSliceVar(&includeBuckets, "include", nil, "Only audit buckets matching these name patterns (glob, or regex prefixed with 're:')")
+	cmd.Flags().StringSliceVar(&excludeBuckets, "exclude", nil, "Do not audit buckets matching these name patterns (glob, or regex prefixed with 're:')")
+	cmd.Flags().StringSliceVar(&includeTags, "tag", nil, "Only audit buckets with all these tags (key=value, value '*' matches any value)")
+	cmd.Flags().StringSliceVar(&excludeTags, "exclude-tag", nil, "Do not audit buckets with any of these tags (key=value, value '*' matches any value)")
+	cmd.Flags().StringSliceVar(&requiredTags, "required-tags", nil, "Tag keys every bucket must have, e.g. 'owner,cost-center'")
+	cmd.Flags().StringArrayVar(&allowedTagValues, "allowed-tag-values", nil, "Allowed values of a tag (key=value1|value2), e.g. 'data-classification=public|internal|confidential'")
+	cmd.Flags().StringVar(&benchmark, "benchmark", "", "CIS AWS Foundations Benchmark version findings refer to (cis-1.4, cis-1.5, cis-2.0, cis-3.0) (default cis-1.4)")
+	cmd.Flags().StringVar(&framework, "framework", "", "Label and group findings by the requirements of this framework (fsbp, nist-800-53, pci-dss, iso-27001)")
+	cmd.Flags().StringVar(&profilesFile, "profiles", "", "YAML file with profiles selecting controls and thresholds per bucket tag or name")
+	cmd.Flags().StringVar(&rulesDir, "rules", "", "Directory of YAML files with custom rules written in CEL, evaluated in addition to the controls")
+	cmd.Flags().StringVar(&suppressionsFile, "suppressions", "", "YAML file with suppressions of failed controls accepted as risk, e.g. for intentionally public buckets")
+	cmd.Flags().StringSliceVar(&objectLockBuckets, "object-lock-buckets", nil, "Bucket name patterns (glob) that require Object Lock, e.g. 'backup-*'")
+	cmd.Flags().StringSliceVar(&objectLockTags, "object-lock-tag", nil, "Bucket tags (key=value) that require Object Lock, e.g. 'tier=backup'")
+	cmd.Flags().StringVar(&objectLockMode, "object-lock-mode", "", "Minimum default retention mode for required Object Lock (GOVERNANCE, COMPLIANCE)")
+	cmd.Flags().Int32Var(&objectLockMinDays, "object-lock-min-days", 0, "Minimum default retention period in days for required Object Lock")
+	cmd.Flags().StringSliceVar(&replicationBuckets, "replication-buckets", nil, "Bucket name patterns (glob) that require replication, e.g. 'dr-*'")
+	cmd.Flags().StringSliceVar(&replicationTags, "replication-tag", nil, "Bucket tags (key=value) that require replication, e.g. 'tier=dr'")
+	cmd.Flags().BoolVar(&replicationCrossRegion, "replication-cross-region", false, "Required replication must target a bucket in another region")
+	cmd.Flags().BoolVar(&replicationCrossAccount, "replication-cross-account", false, "Required replication must target a bucket in another account")
+	cmd.Flags().StringVar(&replicationDestinationAccount, "replication-destination-account", "", "Required replication must target a bucket in this account")
+	cmd.Flags().BoolVar(&replicationKMS, "replication-kms", false, "Required replication must encrypt replicas with a KMS key")
+	cmd.Flags().BoolVar(&replicationDeleteMarkers, "replication-delete-markers", false, "Required replication must replicate delete markers")
+}
+
 func init() {
 	rootCmd.AddCommand(auditCmd)
-	auditCmd.Flags().StringVarP(&outputFormat, "output", "o", "txt", "Define outputFormat report (txt, csv, json, noout)")
+	addRegionFlags(auditCmd)
+	addAuditFlags(auditCmd)
+	auditCmd.Flags().StringSliceVar(&configSnapshots, "from-config-snapshot", nil, "Audit offline from AWS Config snapshot or configuration history files (JSON) instead of calling AWS")
+	auditCmd.Flags().StringVar(&dumpStateFile, "dump-state", "", "Write the collected configuration of the buckets to this state file (JSON), e.g. to evaluate it again with --from-state")
+	auditCmd.Flags().StringVar(&stateFile, "from-state", "", "Audit offline from a state file written with --dump-state instead of calling AWS")
+	auditCmd.Flags().StringVar(&baselineFile, "write-baseline", "", "Write a suppressions file accepting all current failures, e.g. to only report new failures")
+	auditCmd.Flags().StringVar(&historyFile, "history", "", "Record the run in this history file (created if missing), see the history command")
+	auditCmd.Flags().IntVar(&baselineDays, "baseline-days", 90, "Days until the suppressions written by --write-baseline expire")
 }
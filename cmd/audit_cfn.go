@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rollwagen/s3-cisbench/internal/cloudformation"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cfnAccountID  string
+	cfnRegion     string
+	cfnParameters []string
+)
+
+// auditCfnCmd represents the audit-cfn command.
+var auditCfnCmd = &cobra.Command{
+	Use:   "audit-cfn <template> [<template>...]",
+	Short: "Audit S3 buckets of CloudFormation templates before they are deployed",
+	Long: `Audit the S3 buckets of CloudFormation templates in YAML or JSON format before they are deployed.
+
+'AWS::S3::Bucket' and 'AWS::S3::BucketPolicy' resources are extracted and intrinsic functions like
+!Ref, !Sub, !GetAtt or !If are resolved best-effort; findings refer to the logical resource IDs and
+template line numbers.`,
+	Args: cobra.MinimumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := parseParameters(cfnParameters); err != nil {
+			return err
+		}
+		return validateAuditFlags(cmd, args)
+	},
+	Run: func(_ *cobra.Command, args []string) {
+		parameters, _ := parseParameters(cfnParameters) // validated in PreRunE
+		options := cloudformation.Options{AccountID: cfnAccountID, Region: cfnRegion, Parameters: parameters}
+		collected, err := cloudformation.LoadTemplates(args, options)
+		if err != nil {
+			log.Errorf("Error reading CloudFormation template: %v", err)
+			os.Exit(1)
+		}
+		printReports(evaluateReports(collected, ""))
+	},
+}
+
+// parseParameters converts 'key=value' strings into template parameter values.
+func parseParameters(parameters []string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, p := range parameters {
+		key, value, found := strings.Cut(p, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid parameter '%s': expected key=value", p)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+func init() {
+	rootCmd.AddCommand(auditCfnCmd)
+	addAuditFlags(auditCfnCmd)
+	auditCfnCmd.Flags().StringVar(&cfnAccountID, "account-id", "", "AWS account the stack is deployed to, for AWS::AccountId and cross-account checks")
+	auditCfnCmd.Flags().StringVar(&cfnRegion, "region", "", "AWS region the stack is deployed to, for AWS::Region")
+	auditCfnCmd.Flags().StringSliceVar(&cfnParameters, "parameter", nil, "Template parameter value (key=value), overriding the parameter's default")
+}
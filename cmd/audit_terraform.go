@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/rollwagen/s3-cisbench/internal/terraform"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var terraformAccountID string
+
+// auditTerraformCmd represents the audit-terraform command.
+var auditTerraformCmd = &cobra.Command{
+	Use:   "audit-terraform <plan.json>",
+	Short: "Audit S3 buckets of a Terraform plan before they are deployed",
+	Long: `Audit the S3 buckets resulting from a Terraform plan before they are deployed. The plan is read in JSON format:
+
+  terraform plan -out tfplan && terraform show -json tfplan > plan.json
+
+The bucket configuration is reconstructed from 'aws_s3_bucket' and the resources configuring it, like
+'aws_s3_bucket_policy' or 'aws_s3_bucket_versioning'; findings refer to the Terraform resource addresses.
+A state in JSON format ('terraform show -json') can be audited as well.`,
+	Args:    cobra.ExactArgs(1),
+	PreRunE: validateAuditFlags,
+	Run: func(_ *cobra.Command, args []string) {
+		collected, err := terraform.LoadPlan(args[0], terraformAccountID)
+		if err != nil {
+			log.Errorf("Error reading Terraform plan: %v", err)
+			os.Exit(1)
+		}
+		printReports(evaluateReports(collected, ""))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(auditTerraformCmd)
+	addAuditFlags(auditTerraformCmd)
+	auditTerraformCmd.Flags().StringVar(&terraformAccountID, "account-id", "", "AWS account the plan is applied to, for cross-account checks (default: the provider's 'allowed_account_ids')")
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rollwagen/s3-cisbench/internal/audit"
+	"github.com/rollwagen/s3-cisbench/internal/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configFile string
+
+	// settings of the configuration file without a flag
+	enabledControls    []audit.ControlID
+	severityOverrides  map[audit.ControlID]audit.Severity
+	configProfiles     []audit.Profile
+	configSuppressions []audit.Suppression
+)
+
+// configCmd represents the config command.
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the configuration file",
+	Long: `Manage the configuration file ` + config.FileName + `, searched in the current directory and in
+$XDG_CONFIG_HOME (default ~/.config) unless given with --config. Flags override the values of the file.`,
+	PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+		setUpLogging(debug) // the configuration file is not applied, it may be invalid
+		return nil
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [<file>]",
+	Short: "Validate the configuration file and report errors with their line",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		path := configFile
+		if len(args) != 0 {
+			path = args[0]
+		}
+		if path == "" {
+			path = config.Find()
+		}
+		if path == "" {
+			log.Errorf("No configuration file %s found", config.FileName)
+			os.Exit(1)
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			log.Errorf("Error reading configuration file: %v", err)
+			os.Exit(1)
+		}
+		if _, errs := config.Parse(b, filepath.Dir(path)); len(errs) != 0 {
+			for _, e := range errs {
+				_, _ = fmt.Fprintf(os.Stderr, "%s:%s\n", path, strings.TrimPrefix(e.Error(), "line "))
+			}
+			os.Exit(1)
+		}
+		fmt.Printf("%s is valid\n", path)
+	},
+}
+
+// loadConfig reads the configuration file, if any, and applies the values not overridden by flags.
+func loadConfig(cmd *cobra.Command) error {
+	path := configFile
+	if path == "" {
+		path = config.Find()
+	}
+	if path == "" {
+		return nil
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	applyConfig(cmd, cfg)
+	return nil
+}
+
+// fromConfig sets the flag's variable to the configured value, unless the flag was given or the command
+// does not define the flag, e.g. 'output.history' must not make 'policy lint' record a history.
+func fromConfig[T any](cmd *cobra.Command, flag string, target *T, value T, configured bool) {
+	if configured && cmd.Flags().Lookup(flag) != nil && !cmd.Flags().Changed(flag) {
+		*target = value
+	}
+}
+
+// tagFilters converts a tag map into the 'key=value' format of the tag flags.
+func tagFilters(tags map[string]string) []string {
+	var filters []string
+	for key, value := range tags {
+		filters = append(filters, key+"="+value)
+	}
+	sort.Strings(filters)
+	return filters
+}
+
+func applyConfig(cmd *cobra.Command, cfg *config.Config) {
+	fromConfig(cmd, "debug", &debug, cfg.Debug, cfg.Debug)
+	fromConfig(cmd, "endpoint-url", &endpointURL, cfg.AWS.EndpointURL, cfg.AWS.EndpointURL != "")
+	fromConfig(cmd, "path-style", &pathStyle, cfg.AWS.PathStyle, cfg.AWS.PathStyle)
+	fromConfig(cmd, "no-verify-ssl", &noVerifySSL, cfg.AWS.NoVerifySSL, cfg.AWS.NoVerifySSL)
+	for env, value := range map[string]string{"AWS_PROFILE": cfg.AWS.Profile, "AWS_REGION": cfg.AWS.Region} {
+		if _, set := os.LookupEnv(env); !set && value != "" {
+			_ = os.Setenv(env, value)
+		}
+	}
+
+	fromConfig(cmd, "output", &outputFormat, cfg.Output.Format, cfg.Output.Format != "")
+	fromConfig(cmd, "output-file", &outputFile, cfg.Output.File, cfg.Output.File != "")
+	fromConfig(cmd, "emit", &emitFormat, cfg.Output.Emit, cfg.Output.Emit != "")
+	fromConfig(cmd, "history", &historyFile, cfg.Output.History, cfg.Output.History != "")
+
+	fromConfig(cmd, "benchmark", &benchmark, string(cfg.Benchmark), cfg.Benchmark != "")
+	fromConfig(cmd, "framework", &framework, string(cfg.Framework), cfg.Framework != "")
+
+	filters := cfg.Filters
+	fromConfig(cmd, "include", &includeBuckets, filters.Include, len(filters.Include) != 0)
+	fromConfig(cmd, "exclude", &excludeBuckets, filters.Exclude, len(filters.Exclude) != 0)
+	fromConfig(cmd, "tag", &includeTags, tagFilters(filters.Tags), len(filters.Tags) != 0)
+	fromConfig(cmd, "exclude-tag", &excludeTags, tagFilters(filters.ExcludeTags), len(filters.ExcludeTags) != 0)
+	fromConfig(cmd, "regions", &regions, filters.Regions, len(filters.Regions) != 0)
+	fromConfig(cmd, "exclude-regions", &excludeRegions, filters.ExcludeRegions, len(filters.ExcludeRegions) != 0)
+
+	fromConfig(cmd, "required-tags", &requiredTags, cfg.RequiredTags, len(cfg.RequiredTags) != 0)
+	var allowed []string
+	for key, values := range cfg.AllowedTagValues {
+		allowed = append(allowed, key+"="+strings.Join(values, "|"))
+	}
+	sort.Strings(allowed)
+	fromConfig(cmd, "allowed-tag-values", &allowedTagValues, allowed, len(allowed) != 0)
+
+	if req := cfg.ObjectLock; req != nil {
+		fromConfig(cmd, "object-lock-buckets", &objectLockBuckets, req.Selector.Names, len(req.Selector.Names) != 0)
+		fromConfig(cmd, "object-lock-tag", &objectLockTags, tagFilters(req.Selector.Tags), len(req.Selector.Tags) != 0)
+		fromConfig(cmd, "object-lock-mode", &objectLockMode, req.Mode, req.Mode != "")
+		fromConfig(cmd, "object-lock-min-days", &objectLockMinDays, req.MinRetentionDays, req.MinRetentionDays != 0)
+	}
+	if req := cfg.Replication; req != nil {
+		fromConfig(cmd, "replication-buckets", &replicationBuckets, req.Selector.Names, len(req.Selector.Names) != 0)
+		fromConfig(cmd, "replication-tag", &replicationTags, tagFilters(req.Selector.Tags), len(req.Selector.Tags) != 0)
+		fromConfig(cmd, "replication-cross-region", &replicationCrossRegion, req.CrossRegion, req.CrossRegion)
+		fromConfig(cmd, "replication-cross-account", &replicationCrossAccount, req.CrossAccount, req.CrossAccount)
+		fromConfig(cmd, "replication-destination-account", &replicationDestinationAccount, req.DestinationAccount, req.DestinationAccount != "")
+		fromConfig(cmd, "replication-kms", &replicationKMS, req.ReplicaKMS, req.ReplicaKMS)
+		fromConfig(cmd, "replication-delete-markers", &replicationDeleteMarkers, req.DeleteMarkerReplication, req.DeleteMarkerReplication)
+	}
+
+	// inline profiles and suppressions are replaced by the files given as flags
+	fromConfig(cmd, "profiles", &profilesFile, cfg.ProfilesFile, cfg.ProfilesFile != "")
+	fromConfig(cmd, "profiles", &configProfiles, cfg.Profiles, len(cfg.Profiles) != 0)
+	fromConfig(cmd, "suppressions", &suppressionsFile, cfg.SuppressionsFile, cfg.SuppressionsFile != "")
+	fromConfig(cmd, "rules", &rulesDir, cfg.Rules, cfg.Rules != "")
+	fromConfig(cmd, "suppressions", &configSuppressions, cfg.Suppressions, len(cfg.Suppressions) != 0)
+
+	enabledControls = cfg.Controls
+	severityOverrides = cfg.Severities
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd)
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Configuration file (default "+config.FileName+" in the current directory or $XDG_CONFIG_HOME)")
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/rollwagen/s3-cisbench/internal/diff"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var diffOutputFormat string
+
+// diffCmd represents the diff command.
+var diffCmd = &cobra.Command{
+	Use:   "diff <old.json> <new.json>",
+	Short: "Show what changed between two audit reports",
+	Long: `Show what changed between two JSON audit reports ('audit -o json'), e.g. of nightly runs: new and deleted
+buckets, controls that regressed to failed and controls that were fixed. Buckets are matched by account,
+region and name.`,
+	Args: cobra.ExactArgs(2),
+	PreRunE: func(_ *cobra.Command, _ []string) error {
+		switch diffOutputFormat {
+		case "txt", "json", "md":
+			return nil
+		}
+		return fmt.Errorf("invalid output format '%s': expected txt, json or md", diffOutputFormat)
+	},
+	Run: func(_ *cobra.Command, args []string) {
+		oldReports, err := diff.LoadReport(args[0])
+		if err != nil {
+			log.Errorf("Error reading old report: %v", err)
+			os.Exit(1)
+		}
+		newReports, err := diff.LoadReport(args[1])
+		if err != nil {
+			log.Errorf("Error reading new report: %v", err)
+			os.Exit(1)
+		}
+
+		d := diff.Compare(oldReports, newReports)
+		switch diffOutputFormat {
+		case "json":
+			b, _ := json.MarshalIndent(d, "", "  ")
+			fmt.Println(string(b))
+		case "md":
+			_ = d.WriteMarkdown(os.Stdout)
+		default:
+			_ = d.WriteText(os.Stdout)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().StringVarP(&diffOutputFormat, "output", "o", "txt", "Define output format (txt, json, md)")
+}
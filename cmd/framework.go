@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/rollwagen/s3-cisbench/internal/audit"
+	"github.com/spf13/cobra"
+)
+
+var frameworkOutputFormat string
+
+// frameworkCmd represents the framework command.
+var frameworkCmd = &cobra.Command{
+	Use:   "framework [<framework>]",
+	Short: "Show which requirements of compliance frameworks the audit can evaluate",
+	Long: `Show the S3 related requirements of the compliance frameworks supported by 'audit --framework', the controls
+evaluating each requirement and the requirements that cannot be evaluated by this tool. Without a framework,
+the coverage of all frameworks is shown.`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: frameworkNames(),
+	PreRunE: func(_ *cobra.Command, args []string) error {
+		if frameworkOutputFormat != "txt" && frameworkOutputFormat != "json" {
+			return fmt.Errorf("invalid output format '%s': expected txt or json", frameworkOutputFormat)
+		}
+		if len(args) == 1 {
+			return audit.ValidateFramework(audit.Framework(args[0]))
+		}
+		return nil
+	},
+	Run: func(_ *cobra.Command, args []string) {
+		frameworks := audit.Frameworks()
+		if len(args) == 1 {
+			frameworks = []audit.Framework{audit.Framework(args[0])}
+		}
+
+		if frameworkOutputFormat == "json" {
+			type frameworkCoverage struct {
+				Framework    audit.Framework     `json:"framework"`
+				Name         string              `json:"name"`
+				Evaluated    int                 `json:"evaluated"`
+				Total        int                 `json:"total"`
+				Requirements []audit.Requirement `json:"requirements"`
+			}
+			coverages := []frameworkCoverage{}
+			for _, f := range frameworks {
+				evaluated, total := f.Coverage()
+				coverages = append(coverages, frameworkCoverage{f, f.Name(), evaluated, total, f.Requirements()})
+			}
+			printJSON(coverages)
+			return
+		}
+
+		for _, f := range frameworks {
+			evaluated, total := f.Coverage()
+			_, _ = color.New(color.FgHiBlue, color.Bold).Printf("%s (%s): %d of %d requirements evaluated\n", f.Name(), f, evaluated, total)
+			for _, r := range f.Requirements() {
+				if len(r.Controls) == 0 {
+					_, _ = color.New(color.FgWhite).Printf("  ✖ %-7s %s (not evaluated)\n", r.ID, r.Title)
+					continue
+				}
+				var controls []string
+				for _, control := range r.Controls {
+					controls = append(controls, string(control))
+				}
+				_, _ = color.New(color.FgHiGreen).Printf("  ✔ %-7s %s", r.ID, r.Title)
+				fmt.Println(" (" + strings.Join(controls, ", ") + ")")
+			}
+			fmt.Println()
+		}
+	},
+}
+
+func frameworkNames() []string {
+	var names []string
+	for _, f := range audit.Frameworks() {
+		names = append(names, string(f))
+	}
+	return names
+}
+
+func init() {
+	rootCmd.AddCommand(frameworkCmd)
+	frameworkCmd.Flags().StringVarP(&frameworkOutputFormat, "output", "o", "txt", "Define output format (txt, json)")
+}
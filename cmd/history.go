@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/rollwagen/s3-cisbench/internal/audit"
+	"github.com/rollwagen/s3-cisbench/internal/history"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyDB           string
+	historyOutputFormat string
+	historyAccountID    string
+	historyLast         int
+)
+
+// historyCmd represents the history command.
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show trends of the audit runs recorded with 'audit --history'",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := rootCmd.PersistentPreRunE(cmd, args); err != nil {
+			return err
+		}
+		if historyOutputFormat != "txt" && historyOutputFormat != "json" {
+			return fmt.Errorf("invalid output format '%s': expected txt or json", historyOutputFormat)
+		}
+		return nil
+	},
+}
+
+var historyRunsCmd = &cobra.Command{
+	Use:   "runs",
+	Short: "List the recorded audit runs",
+	Args:  cobra.NoArgs,
+	Run: func(_ *cobra.Command, _ []string) {
+		runs := loadRuns()
+		if historyOutputFormat == "json" {
+			type runSummary struct {
+				ID       uint64    `json:"id"`
+				Time     time.Time `json:"time"`
+				Identity string    `json:"identity,omitempty"`
+				Buckets  int       `json:"buckets"`
+				Failed   int       `json:"failed"`
+			}
+			summaries := []runSummary{}
+			for _, run := range runs {
+				summaries = append(summaries, runSummary{run.ID, run.Time, run.Identity, len(run.Reports), run.Failed()})
+			}
+			printJSON(summaries)
+			return
+		}
+
+		c := color.New(color.FgYellow).Add(color.Underline)
+		_, _ = c.Println("  Run  Time                  Buckets  Failed  Identity")
+		for _, run := range runs {
+			fmt.Printf("%5d  %s  %7d  %s  %s\n", run.ID, color.BlueString(run.Time.Local().Format("2006-01-02 15:04:05")),
+				len(run.Reports), color.RedString("%6d", run.Failed()), color.CyanString(run.Identity))
+		}
+	},
+}
+
+var historyBucketCmd = &cobra.Command{
+	Use:   "bucket <bucket name>",
+	Short: "Show the status of a bucket's controls over the recorded runs",
+	Args:  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		runs := loadRuns()
+		if historyLast > 0 && len(runs) > historyLast {
+			runs = runs[len(runs)-historyLast:]
+		}
+		controls := history.BucketHistory(runs, historyAccountID, args[0])
+		if len(controls) == 0 {
+			log.Errorf("Bucket %s not found in history", args[0])
+			os.Exit(1)
+		}
+
+		if historyOutputFormat == "json" {
+			type bucketHistory struct {
+				Runs     []uint64                 `json:"runs"`
+				Times    []time.Time              `json:"times"`
+				Controls []history.ControlHistory `json:"controls"`
+			}
+			h := bucketHistory{Controls: controls}
+			for _, run := range runs {
+				h.Runs = append(h.Runs, run.ID)
+				h.Times = append(h.Times, run.Time)
+			}
+			printJSON(h)
+			return
+		}
+
+		header := fmt.Sprintf("%-22s", "Control")
+		for _, run := range runs {
+			header += fmt.Sprintf(" %5d", run.ID)
+		}
+		_, _ = color.New(color.FgYellow).Add(color.Underline).Println(header)
+		for _, h := range controls {
+			line := fmt.Sprintf("%-22s", h.Control)
+			for _, status := range h.Statuses {
+				switch status {
+				case audit.StatusPass:
+					line += color.GreenString("     ✔")
+				case audit.StatusFail:
+					line += color.RedString("     ✖")
+				case audit.StatusNotApplicable:
+					line += color.YellowString("     -")
+				default:
+					line += "      "
+				}
+			}
+			fmt.Println(line)
+		}
+		first, last := runs[0].Time.Local().Format("2006-01-02"), runs[len(runs)-1].Time.Local().Format("2006-01-02")
+		_, _ = color.New(color.FgYellow).Printf("%d run(s) from %s to %s\n", len(runs), first, last)
+	},
+}
+
+var historyRemediationCmd = &cobra.Command{
+	Use:   "remediation",
+	Short: "Show the time to remediate failed findings",
+	Args:  cobra.NoArgs,
+	Run: func(_ *cobra.Command, _ []string) {
+		remediations := history.TimeToRemediate(loadRuns())
+		summaries := history.Summarize(remediations)
+		if historyOutputFormat == "json" {
+			printJSON(struct {
+				Findings []history.Remediation    `json:"findings"`
+				Controls []history.ControlSummary `json:"controls"`
+			}{remediations, summaries})
+			return
+		}
+
+		now := time.Now()
+		c := color.New(color.FgYellow).Add(color.Underline)
+		_, _ = c.Println("Failed since      Fixed at          Duration  Control               Bucket")
+		for _, r := range remediations {
+			fixedAt := color.RedString("%-16s", "open")
+			if r.FixedAt != nil {
+				fixedAt = color.GreenString(r.FixedAt.Local().Format("2006-01-02 15:04"))
+			}
+			fmt.Printf("%s  %s  %8s  %-20s  %s\n", r.FailedSince.Local().Format("2006-01-02 15:04"), fixedAt,
+				formatDuration(r.Duration(now)), r.Control, color.CyanString(strings.Trim(r.AccountID+"/"+r.Region, "/")+"/"+r.Bucket))
+		}
+
+		fmt.Println()
+		_, _ = c.Println("Control               Fixed   Mean  Median  Open")
+		for _, s := range summaries {
+			mean, median := "-", "-"
+			if s.Fixed != 0 {
+				mean, median = formatDuration(s.Mean), formatDuration(s.Median)
+			}
+			fmt.Printf("%-20s  %5d  %5s  %6s  %s\n", s.Control, s.Fixed, mean, median, color.RedString("%4d", s.Open))
+		}
+	},
+}
+
+// loadRuns returns the runs recorded in the history file.
+func loadRuns() []history.Run {
+	store, err := history.Open(historyDB, true)
+	if err != nil {
+		log.Errorf("Error reading history: %v", err)
+		os.Exit(1)
+	}
+	defer func() { _ = store.Close() }()
+	runs, err := store.Runs()
+	if err != nil {
+		log.Errorf("Error reading history: %v", err)
+		os.Exit(1)
+	}
+	return runs
+}
+
+func printJSON(v any) {
+	b, _ := json.MarshalIndent(v, "", "  ")
+	fmt.Println(string(b))
+}
+
+// formatDuration formats durations of a day or longer in days and hours, e.g. '3d4h'.
+func formatDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return d.Round(time.Second).String()
+	case d < 24*time.Hour:
+		return d.Round(time.Minute).String()
+	}
+	d = d.Round(time.Hour)
+	return fmt.Sprintf("%dd%dh", d/(24*time.Hour), (d%(24*time.Hour))/time.Hour)
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyRunsCmd, historyBucketCmd, historyRemediationCmd)
+	historyCmd.PersistentFlags().StringVar(&historyDB, "db", "", "History file written by 'audit --history'")
+	_ = historyCmd.MarkPersistentFlagRequired("db")
+	historyCmd.PersistentFlags().StringVarP(&historyOutputFormat, "output", "o", "txt", "Define output format (txt, json)")
+	historyBucketCmd.Flags().StringVar(&historyAccountID, "account-id", "", "Only match the bucket in this account")
+	historyBucketCmd.Flags().IntVar(&historyLast, "last", 10, "Show only the last n runs; 0 shows all runs")
+}
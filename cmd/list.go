@@ -1,16 +1,15 @@
 package cmd
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
 
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/smithy-go"
 	"github.com/fatih/color"
+	"github.com/rollwagen/s3-cisbench/internal/aws"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -25,14 +24,7 @@ var listCmd = &cobra.Command{
 }
 
 func PrintAllBuckets() {
-	ctx := context.TODO()
-	cfg, err := config.LoadDefaultConfig(ctx)
-	if err != nil {
-		log.Fatalf("Failed to load AWS SDK configuration: %v", err)
-	}
-	s3Client := s3.NewFromConfig(cfg)
-	log.Debug("Listing buckets")
-	result, err := s3Client.ListBuckets(ctx, &s3.ListBucketsInput{})
+	buckets, err := aws.ListBuckets(regionFilter())
 	if err != nil {
 		var e smithy.APIError
 		if errors.As(err, &e) {
@@ -43,18 +35,32 @@ func PrintAllBuckets() {
 		os.Exit(1)
 	}
 
-	log.Infof("Received %d buckets", len(result.Buckets))
+	log.Infof("Received %d buckets", len(buckets))
 
 	c := color.New(color.FgYellow).Add(color.Underline)
-	_, _ = c.Println("Creation date  Bucket name")
-	for _, bucket := range result.Buckets {
-		fmt.Println("   " + color.BlueString(bucket.CreationDate.Format("2006-01-02")) + "  " + color.CyanString(*bucket.Name))
+	_, _ = c.Println("Creation date  Region          Bucket name")
+	bucketsPerRegion := map[string]int{}
+	for _, bucket := range buckets {
+		region := *bucket.BucketRegion
+		bucketsPerRegion[region]++
+		fmt.Println("   " + color.BlueString(bucket.CreationDate.Format("2006-01-02")) + "  " +
+			color.MagentaString("%-14s", region) + "  " + color.CyanString(*bucket.Name))
 	}
 
+	regions := make([]string, 0, len(bucketsPerRegion))
+	for region := range bucketsPerRegion {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
 	c = color.New(color.FgYellow)
-	_, _ = c.Println(strconv.Itoa(len(result.Buckets)) + " \uF5A7 overall")
+	for _, region := range regions {
+		_, _ = c.Printf("%5d  %s\n", bucketsPerRegion[region], region)
+	}
+	_, _ = c.Println(strconv.Itoa(len(buckets)) + " \uF5A7 overall")
 }
 
 func init() {
 	rootCmd.AddCommand(listCmd)
+	addRegionFlags(listCmd)
 }
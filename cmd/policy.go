@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/rollwagen/s3-cisbench/internal/audit"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lintBucket    string
+	lintAccountID string
+)
+
+// policyCmd represents the policy command.
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Work with S3 bucket policy documents",
+}
+
+// policyLintCmd represents the policy lint command.
+var policyLintCmd = &cobra.Command{
+	Use:   "lint <policy.json>",
+	Short: "Lint a local bucket policy document against the policy controls",
+	Long: `Lint a local bucket policy document against the policy controls (deny HTTP, TLS version, public
+access and cross-account grants) without any call to AWS. Findings are located by JSON pointers into the
+document. Exits with status 1 if any control fails.`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := audit.ValidateBenchmark(audit.Benchmark(benchmark)); err != nil {
+			return err
+		}
+		return audit.ValidateFramework(audit.Framework(framework))
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		policy, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		if !json.Valid(policy) {
+			return fmt.Errorf("%s is not a valid JSON document", args[0])
+		}
+		cmd.SilenceUsage = true
+
+		state := audit.BucketState{Name: lintBucket, Resource: args[0], AccountID: lintAccountID, Policy: string(policy)}
+		report := audit.New(auditOptions()...).EvaluatePolicy(state)
+		printReports([]audit.BucketReport{report})
+
+		for _, f := range report.Findings {
+			if f.Status == audit.StatusFail {
+				os.Exit(1)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(policyCmd)
+	policyCmd.AddCommand(policyLintCmd)
+	policyLintCmd.Flags().StringVar(&lintBucket, "bucket", "", "Name of the bucket the policy is applied to")
+	policyLintCmd.Flags().StringVar(&lintAccountID, "account-id", "", "AWS account owning the bucket, for cross-account checks")
+	policyLintCmd.Flags().StringVarP(&outputFormat, "output", "o", "txt", "Define outputFormat report (txt, csv, json, noout)")
+	policyLintCmd.Flags().StringVar(&benchmark, "benchmark", "", "CIS AWS Foundations Benchmark version findings refer to (cis-1.4, cis-1.5, cis-2.0, cis-3.0) (default cis-1.4)")
+	policyLintCmd.Flags().StringVar(&framework, "framework", "", "Label and group findings by the requirements of this framework (fsbp, nist-800-53, pci-dss, iso-27001)")
+	_ = policyLintCmd.MarkFlagRequired("bucket")
+}
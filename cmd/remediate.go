@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/rollwagen/s3-cisbench/internal/audit"
+	"github.com/rollwagen/s3-cisbench/internal/remediation"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	remediateApply    bool
+	remediateControls []string
+	remediateKMSKeyID string
+)
+
+// remediateCmd represents the remediate command.
+var remediateCmd = &cobra.Command{
+	Use:   "remediate [<bucket name>]",
+	Short: "Remediate failed controls of S3 buckets",
+	Long: `Remediate failed controls of S3 buckets: missing default encryption, 'Block public access' not fully
+enabled, no bucket policy statement denying HTTP requests and versioning disabled.
+
+By default, only the plan of API changes is printed (dry-run). With --apply, the changes are applied after
+confirming each bucket. A statement denying HTTP requests is merged into an existing bucket policy.`,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return getBucketsCompletion(toComplete), cobra.ShellCompDirectiveNoFileComp
+	},
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if outputFormat != "txt" && outputFormat != "json" && !cmd.Flags().Changed("output") {
+			outputFormat = "txt" // e.g. csv reports configured for the audit commands
+		}
+		if outputFormat != "txt" && outputFormat != "json" {
+			return fmt.Errorf("invalid output format '%s' for remediation plans: expected txt or json", outputFormat)
+		}
+		for _, c := range remediateControls {
+			if !slices.Contains(remediation.Controls(), audit.ControlID(c)) {
+				return fmt.Errorf("control '%s' cannot be remediated", c)
+			}
+		}
+		if remediateApply && emitFormat != "" {
+			return errors.New("--apply cannot be combined with --emit")
+		}
+		return validateAuditFlags(cmd, args)
+	},
+	Run: func(_ *cobra.Command, args []string) {
+		buckets := getBuckets(args, func() {})
+		bucketAuditor := audit.New(auditOptions()...)
+		var reports []audit.BucketReport
+		for _, b := range buckets {
+			reports = append(reports, bucketAuditor.Report(b))
+		}
+		if emitFormat != "" {
+			emitRemediation(reports, true)
+			return
+		}
+
+		plans := remediationPlans(reports, true)
+		if outputFormat == "json" {
+			b, _ := json.MarshalIndent(plans, "", "  ")
+			fmt.Println(string(b))
+		}
+
+		stdin := bufio.NewReader(os.Stdin)
+		for _, plan := range plans {
+			if outputFormat == "txt" {
+				printPlan(plan)
+			}
+			if !remediateApply || len(plan.Actions) == 0 {
+				continue
+			}
+			if !confirm(stdin, fmt.Sprintf("Apply %d change(s) to bucket %s?", len(plan.Actions), plan.Bucket)) {
+				fmt.Println("Skipped")
+				continue
+			}
+			if err := plan.Apply(context.Background()); err != nil {
+				log.Errorf("Error remediating bucket %s: %v", plan.Bucket, err)
+				continue
+			}
+			_, _ = color.New(color.FgHiGreen).Println("\t✔ Applied")
+		}
+	},
+}
+
+// remediationPlans returns the remediation plans of the evaluated reports; live is set if the reports were
+// read from AWS by this run.
+func remediationPlans(reports []audit.BucketReport, live bool) []remediation.Plan {
+	options := remediation.Options{KMSKeyID: remediateKMSKeyID, Live: live}
+	for _, c := range remediateControls {
+		options.Controls = append(options.Controls, audit.ControlID(c))
+	}
+
+	var plans []remediation.Plan
+	for _, report := range reports {
+		plan, err := remediation.NewPlan(report, options)
+		if err != nil {
+			log.Errorf("Error planning remediation of bucket %s: %v", report.Name, err)
+			continue
+		}
+		plans = append(plans, plan)
+	}
+	return plans
+}
+
+// emitRemediation writes the remediation of the failed controls of the reports in the emit format.
+func emitRemediation(reports []audit.BucketReport, live bool) {
+	emitter, _ := remediation.NewEmitter(emitFormat) // validated by validateAuditFlags
+	if err := emitter.Emit(remediationPlans(reports, live), os.Stdout); err != nil {
+		log.Errorf("Error emitting remediation: %v", err)
+		os.Exit(1)
+	}
+}
+
+func printPlan(plan remediation.Plan) {
+	fmt.Println()
+	_, _ = color.New(color.FgHiBlue).Add(color.Bold).Printf("  %s", plan.Bucket)
+	fmt.Printf(" (%s)\n", strings.Trim(plan.AccountID+"/"+plan.Region, "/"))
+
+	if len(plan.Actions) == 0 && len(plan.Skipped) == 0 {
+		_, _ = color.New(color.FgHiGreen).Println("\t✔ No changes")
+	}
+	for _, action := range plan.Actions {
+		_, _ = color.New(color.FgHiYellow).Printf("\t~ [%s] %s: ", action.Control, action.Operation)
+		fmt.Println(action.Description)
+		for _, line := range strings.Split(action.Document, "\n") {
+			fmt.Println("\t\t" + line)
+		}
+	}
+	for _, skipped := range plan.Skipped {
+		_, _ = color.New(color.FgHiRed).Printf("\t! skipped %s\n", skipped)
+	}
+}
+
+// confirm asks the question and reports whether it was answered with yes.
+func confirm(stdin *bufio.Reader, question string) bool {
+	fmt.Printf("%s [y/N]: ", question)
+	answer, _ := stdin.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func init() {
+	rootCmd.AddCommand(remediateCmd)
+	addRegionFlags(remediateCmd)
+	addAuditFlags(remediateCmd)
+	remediateCmd.Flags().BoolVar(&remediateApply, "apply", false, "Apply the remediation plan, confirming each bucket")
+	remediateCmd.Flags().StringSliceVar(&remediateControls, "controls", nil, "Only remediate these controls (encryption, block-public-access, deny-http, versioning)")
+	remediateCmd.Flags().StringVar(&remediateKMSKeyID, "kms-key-id", "", "KMS key for default encryption instead of SSE-S3")
+}
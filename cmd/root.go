@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/rollwagen/s3-cisbench/internal/aws"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -11,6 +12,13 @@ import (
 // if debug logging is on or off.
 var debug bool
 
+// S3 compatible endpoint settings, e.g. for MinIO, Ceph RGW or LocalStack.
+var (
+	endpointURL string
+	pathStyle   bool
+	noVerifySSL bool
+)
+
 // rootCmd represents the base command when called without any subcommands.
 var rootCmd = &cobra.Command{
 	Use:   "s3-cisbench",
@@ -27,7 +35,14 @@ var rootCmd = &cobra.Command{
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(cmd); err != nil {
+			return err
+		}
 		setUpLogging(debug)
+		if noVerifySSL && endpointURL == "" {
+			return fmt.Errorf("--no-verify-ssl requires --endpoint-url; certificates of AWS are always verified")
+		}
+		configureEndpoint()
 
 		return nil
 	}
@@ -39,6 +54,13 @@ func Execute() {
 
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&debug, "debug", "d", false, "Enable verbose logging; recommende to only run with -o noout")
+	rootCmd.PersistentFlags().StringVar(&endpointURL, "endpoint-url", "", "Use an S3 compatible endpoint (e.g. MinIO, Ceph RGW, LocalStack) instead of AWS S3")
+	rootCmd.PersistentFlags().BoolVar(&pathStyle, "path-style", false, "Use path style bucket addressing with --endpoint-url")
+	rootCmd.PersistentFlags().BoolVar(&noVerifySSL, "no-verify-ssl", false, "Do not verify TLS certificates of the S3 endpoint")
+}
+
+func configureEndpoint() {
+	aws.SetEndpoint(aws.Endpoint{URL: endpointURL, PathStyle: pathStyle, SkipVerify: noVerifySSL})
 }
 
 func setUpLogging(debug bool) {
@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/rollwagen/s3-cisbench/internal/aws"
+	log "github.com/sirupsen/logrus"
+)
+
+// ACLGrant is a single grant of a bucket ACL.
+type ACLGrant struct {
+	Grantee    string `json:"grantee"`    // canonical user ID, email address or group URI
+	Type       string `json:"type"`       // CanonicalUser, AmazonCustomerByEmail or Group
+	Permission string `json:"permission"` // e.g. FULL_CONTROL, READ, WRITE
+}
+
+// ACLReport holds the access control list and object ownership setting of a bucket.
+type ACLReport struct {
+	Owner           string     `json:"owner,omitempty"`           // canonical user ID of the bucket owner
+	ObjectOwnership string     `json:"objectOwnership,omitempty"` // BucketOwnerEnforced disables ACLs
+	Grants          []ACLGrant `json:"grants,omitempty"`
+}
+
+func collectACL(s3Client *s3.Client, bucketState *BucketState, logBucket *log.Entry) {
+	bucketName := bucketState.Name
+	expectedBucketOwner := aws.ExpectedBucketOwner(bucketState.AccountID)
+
+	ownershipOutput, err := s3Client.GetBucketOwnershipControls(context.TODO(),
+		&s3.GetBucketOwnershipControlsInput{Bucket: &bucketName, ExpectedBucketOwner: expectedBucketOwner})
+	if err != nil {
+		// api error OwnershipControlsNotFoundError: The bucket ownership controls were not found
+		var ae smithy.APIError
+		if errors.As(err, &ae) {
+			logBucket.Debugf("%s:  %s", ae.ErrorCode(), ae.ErrorMessage())
+		}
+	} else if controls := ownershipOutput.OwnershipControls; controls != nil && len(controls.Rules) != 0 {
+		bucketState.ACL.ObjectOwnership = string(controls.Rules[0].ObjectOwnership)
+	}
+
+	aclOutput, err := s3Client.GetBucketAcl(context.TODO(), &s3.GetBucketAclInput{Bucket: &bucketName, ExpectedBucketOwner: expectedBucketOwner})
+	if err != nil {
+		var ae smithy.APIError
+		if errors.As(err, &ae) {
+			logBucket.Debugf("%s:  %s", ae.ErrorCode(), ae.ErrorMessage())
+		}
+		return
+	}
+	if aclOutput.Owner != nil && aclOutput.Owner.ID != nil {
+		bucketState.ACL.Owner = *aclOutput.Owner.ID
+	}
+	for _, grant := range aclOutput.Grants {
+		if grant.Grantee == nil {
+			continue
+		}
+		var grantee string
+		for _, value := range []*string{grant.Grantee.ID, grant.Grantee.EmailAddress, grant.Grantee.URI} {
+			if value != nil {
+				grantee = *value
+				break
+			}
+		}
+		bucketState.ACL.Grants = append(bucketState.ACL.Grants,
+			ACLGrant{Grantee: grantee, Type: string(grant.Grantee.Type), Permission: string(grant.Permission)})
+	}
+	logBucket.Debugf("ACL: %+v", bucketState.ACL)
+}
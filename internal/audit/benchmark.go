@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Benchmark is a version of the CIS AWS Foundations Benchmark findings refer to.
+type Benchmark string
+
+const (
+	BenchmarkCIS14 Benchmark = "cis-1.4"
+	BenchmarkCIS15 Benchmark = "cis-1.5"
+	BenchmarkCIS20 Benchmark = "cis-2.0"
+	BenchmarkCIS30 Benchmark = "cis-3.0"
+
+	DefaultBenchmark = BenchmarkCIS14
+)
+
+// BenchmarkItem is an item of a benchmark version a control implements.
+type BenchmarkItem struct {
+	Benchmark Benchmark `json:"benchmark"`
+	Item      string    `json:"item"` // e.g. '2.1.1'
+	Title     string    `json:"title"`
+}
+
+type benchmarkVersion struct {
+	benchmark Benchmark
+	version   string
+	items     map[ControlID]BenchmarkItem
+}
+
+// benchmarkVersions maps the controls to the storage items of each version: v2.0.0 dropped the
+// encryption-at-rest item, as S3 encrypts all new objects since January 2023, and renumbered the others.
+var benchmarkVersions = []benchmarkVersion{
+	{BenchmarkCIS14, "v1.4.0", map[ControlID]BenchmarkItem{
+		ControlEncryption:        {Item: "2.1.1", Title: "Ensure all S3 buckets employ encryption-at-rest"},
+		ControlDenyHTTP:          {Item: "2.1.2", Title: "Ensure S3 Bucket Policy is set to deny HTTP requests"},
+		ControlMFADelete:         {Item: "2.1.3", Title: "Ensure MFA Delete is enabled on S3 buckets"},
+		ControlBlockPublicAccess: {Item: "2.1.5", Title: "Ensure that S3 Buckets are configured with 'Block public access'"},
+	}},
+	{BenchmarkCIS15, "v1.5.0", map[ControlID]BenchmarkItem{
+		ControlEncryption:        {Item: "2.1.1", Title: "Ensure all S3 buckets employ encryption-at-rest"},
+		ControlDenyHTTP:          {Item: "2.1.2", Title: "Ensure S3 Bucket Policy is set to deny HTTP requests"},
+		ControlMFADelete:         {Item: "2.1.3", Title: "Ensure MFA Delete is enabled on S3 buckets"},
+		ControlBlockPublicAccess: {Item: "2.1.5", Title: "Ensure that S3 Buckets are configured with 'Block public access (bucket settings)'"},
+	}},
+	{BenchmarkCIS20, "v2.0.0", map[ControlID]BenchmarkItem{
+		ControlDenyHTTP:          {Item: "2.1.1", Title: "Ensure S3 Bucket Policy is set to deny HTTP requests"},
+		ControlMFADelete:         {Item: "2.1.2", Title: "Ensure MFA Delete is enabled on S3 buckets"},
+		ControlBlockPublicAccess: {Item: "2.1.4", Title: "Ensure that S3 Buckets are configured with 'Block public access (bucket settings)'"},
+	}},
+	{BenchmarkCIS30, "v3.0.0", map[ControlID]BenchmarkItem{
+		ControlDenyHTTP:          {Item: "2.1.1", Title: "Ensure S3 Bucket Policy is set to deny HTTP requests"},
+		ControlMFADelete:         {Item: "2.1.2", Title: "Ensure MFA Delete is enabled on S3 buckets"},
+		ControlBlockPublicAccess: {Item: "2.1.4", Title: "Ensure that S3 is configured with 'Block Public Access' enabled"},
+	}},
+}
+
+// Benchmarks returns the supported benchmark versions.
+func Benchmarks() []Benchmark {
+	var benchmarks []Benchmark
+	for _, v := range benchmarkVersions {
+		benchmarks = append(benchmarks, v.benchmark)
+	}
+	return benchmarks
+}
+
+func (b Benchmark) version() (benchmarkVersion, bool) {
+	for _, v := range benchmarkVersions {
+		if v.benchmark == b {
+			return v, true
+		}
+	}
+	return benchmarkVersion{}, false
+}
+
+// ValidateBenchmark returns an error if the benchmark is not supported; empty selects the default.
+func ValidateBenchmark(b Benchmark) error {
+	if _, ok := b.version(); ok || b == "" {
+		return nil
+	}
+	var names []string
+	for _, benchmark := range Benchmarks() {
+		names = append(names, string(benchmark))
+	}
+	return fmt.Errorf("invalid benchmark '%s': expected one of %s", b, strings.Join(names, ", "))
+}
+
+// Version returns the benchmark's version as published, e.g. 'v1.4.0'.
+func (b Benchmark) Version() string {
+	v, _ := b.version()
+	return v.version
+}
+
+// Item returns the benchmark's item the control implements; non-CIS controls have none.
+func (b Benchmark) Item(id ControlID) (BenchmarkItem, bool) {
+	v, _ := b.version()
+	item, ok := v.items[id]
+	item.Benchmark = b
+	return item, ok
+}
+
+// applyBenchmark sets the benchmark item on the findings of controls implementing one.
+func (r *BucketReport) applyBenchmark(b Benchmark) {
+	for i, f := range r.Findings {
+		if item, ok := b.Item(f.Control); ok {
+			r.Findings[i].Benchmark = &item
+		}
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/smithy-go"
+	"github.com/rollwagen/s3-cisbench/internal/aws"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -30,9 +32,26 @@ type statement struct {
 type condition map[string]json.RawMessage
 
 type policyDocument struct {
-	Version    string      `json:"Version"`
-	ID         string      `json:"ID,omitempty"`
-	Statements []statement `json:"statement"`
+	Version    string     `json:"Version"`
+	ID         string     `json:"ID,omitempty"`
+	Statements statements `json:"statement"`
+}
+
+// statements is needed b/c AWS allows a single statement object as well as a list of statements.
+type statements []statement
+
+func (s *statements) UnmarshalJSON(b []byte) error {
+	var single statement
+	if err := json.Unmarshal(b, &single); err == nil {
+		*s = statements{single}
+		return nil
+	}
+	var list []statement
+	if err := json.Unmarshal(b, &list); err != nil {
+		return fmt.Errorf("could not unmarshall json: %v", err)
+	}
+	*s = list
+	return nil
 }
 
 // Value is needed b/c AWS allows string or []string as value; convert everything to []string to avoid casting.
@@ -53,7 +72,7 @@ func (v *Value) UnmarshalJSON(b []byte) error {
 	case []interface{}:
 		var items []string
 		for _, item := range valueType {
-			items = append(items, fmt.Sprintf("%valueType", item))
+			items = append(items, fmt.Sprintf("%v", item))
 		}
 
 		value = items
@@ -67,16 +86,21 @@ func (v *Value) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
-type BucketReport struct {
-	Name                        string `json:"name"`
-	AccountID                   string `json:"accountId"`
-	Region                      string `json:"region"`
-	ServerSideEncryptionEnabled bool   `json:"serverSideEncryptionEnabled"`
+// BucketState is the configuration of a bucket as collected from AWS or an offline source, without any
+// judgement; it can be serialized to evaluate the controls later, see WriteStates.
+type BucketState struct {
+	Name                        string            `json:"name"`
+	Resource                    string            `json:"resource,omitempty"` // defining resource for pre-deployment audits, e.g. a Terraform address
+	AccountID                   string            `json:"accountId"`
+	Region                      string            `json:"region"`
+	Tags                        map[string]string `json:"tags,omitempty"`
+	ServerSideEncryptionEnabled bool              `json:"serverSideEncryptionEnabled"`
+	EncryptionAlgorithm         string            `json:"encryptionAlgorithm,omitempty"`
 	// EncryptionKeyType           KeyType `json:"-"`
-	CustomerManagedKey bool `json:"customerManagedKey"`
-	VersioningEnabled  bool `json:"versioningEnabled"`
-	MFADelete          bool `json:"mfaDelete"`
-	PolicyDenyHTTP     bool `json:"policyDenyHttp"`
+	CustomerManagedKey bool   `json:"customerManagedKey"`
+	VersioningEnabled  bool   `json:"versioningEnabled"`
+	MFADelete          bool   `json:"mfaDelete"`
+	Policy             string `json:"policy,omitempty"` // bucket policy document
 
 	BlockPublicAccess struct {
 		BlockPublicAcls       bool `json:"blockPublicAcls"`
@@ -84,6 +108,27 @@ type BucketReport struct {
 		IgnorePublicAcls      bool `json:"ignorePublicAcls"`
 		RestrictPublicBuckets bool `json:"restrictPublicBuckets"`
 	}
+
+	ObjectLock   ObjectLockReport   `json:"objectLock"`
+	Lifecycle    LifecycleReport    `json:"lifecycle"`
+	Replication  ReplicationReport  `json:"replication"`
+	Website      WebsiteReport      `json:"website"`
+	CORS         CORSReport         `json:"cors"`
+	Notification NotificationReport `json:"notification"`
+	ACL          ACLReport          `json:"acl"`
+
+	Unavailable map[ControlID]string `json:"unavailable,omitempty"` // controls that cannot be evaluated, with the reason why
+	Resources   map[ControlID]string `json:"resources,omitempty"`   // resources defining the configuration evaluated by a control
+}
+
+// BucketReport is the result of evaluating the controls against the state of a bucket.
+type BucketReport struct {
+	BucketState
+
+	Profile        string `json:"profile,omitempty"`
+	PolicyDenyHTTP bool   `json:"policyDenyHttp"`
+
+	Findings []Finding `json:"findings"`
 }
 
 // type KeyType uint8
@@ -93,56 +138,264 @@ type BucketReport struct {
 //	KeyTypeAWSCustomerManagedKey
 // )
 
-type BucketAuditor struct{}
+type BucketAuditor struct {
+	objectLockRequirements  []ObjectLockRequirement
+	replicationRequirements []ReplicationRequirement
+	tagPolicy               TagPolicy
+	profiles                []Profile
+	suppressions            []Suppression
+	controls                []ControlID
+	severities              map[ControlID]Severity
+	benchmark               Benchmark
+	framework               Framework
+	rules                   []Rule
+}
+
+// Option configures optional behaviour of a BucketAuditor.
+type Option func(*BucketAuditor)
+
+// WithObjectLockRequirement adds an Object Lock requirement; the first requirement matching a bucket applies.
+func WithObjectLockRequirement(req ObjectLockRequirement) Option {
+	return func(auditor *BucketAuditor) {
+		auditor.objectLockRequirements = append(auditor.objectLockRequirements, req)
+	}
+}
+
+// WithReplicationRequirement adds a replication requirement; the first requirement matching a bucket applies.
+func WithReplicationRequirement(req ReplicationRequirement) Option {
+	return func(auditor *BucketAuditor) {
+		auditor.replicationRequirements = append(auditor.replicationRequirements, req)
+	}
+}
+
+// WithTagPolicy sets the required tags and allowed tag values every bucket is checked against.
+func WithTagPolicy(policy TagPolicy) Option {
+	return func(auditor *BucketAuditor) {
+		auditor.tagPolicy = policy
+	}
+}
+
+// WithProfiles sets the profiles; the first profile matching a bucket is applied.
+func WithProfiles(profiles []Profile) Option {
+	return func(auditor *BucketAuditor) {
+		auditor.profiles = profiles
+	}
+}
+
+// WithSuppressions sets the suppressions marking failed findings as accepted risk.
+func WithSuppressions(suppressions []Suppression) Option {
+	return func(auditor *BucketAuditor) {
+		auditor.suppressions = suppressions
+	}
+}
+
+// WithControls enables only the given controls; findings of other controls are not reported.
+func WithControls(ids []ControlID) Option {
+	return func(auditor *BucketAuditor) {
+		auditor.controls = ids
+	}
+}
+
+// WithSeverities overrides the severities of failed findings per control.
+func WithSeverities(severities map[ControlID]Severity) Option {
+	return func(auditor *BucketAuditor) {
+		auditor.severities = severities
+	}
+}
+
+// WithBenchmark sets the CIS benchmark version findings refer to; the default is DefaultBenchmark.
+func WithBenchmark(benchmark Benchmark) Option {
+	return func(auditor *BucketAuditor) {
+		auditor.benchmark = benchmark
+	}
+}
+
+// WithFramework labels findings with the requirements of the framework their control evaluates.
+func WithFramework(framework Framework) Option {
+	return func(auditor *BucketAuditor) {
+		auditor.framework = framework
+	}
+}
+
+// WithRules adds custom rules evaluated in addition to the controls.
+func WithRules(rules []Rule) Option {
+	return func(auditor *BucketAuditor) {
+		auditor.rules = append(auditor.rules, rules...)
+	}
+}
+
+func New(opts ...Option) *BucketAuditor {
+	auditor := &BucketAuditor{benchmark: DefaultBenchmark}
+	for _, opt := range opts {
+		opt(auditor)
+	}
+	return auditor
+}
+
+// PolicyDeniesHTTP reports whether the bucket policy has a statement denying all requests not using HTTPS.
+func PolicyDeniesHTTP(bucketName string, policy string, logBucket *log.Entry) bool {
+	policyDenyHTTP := false
+	var policyDocument policyDocument
+	err := json.Unmarshal([]byte(policy), &policyDocument)
+	if err != nil {
+		logBucket.Errorf("Error unmarshalling json %v", err)
+	}
+	logPolicy := logBucket.WithFields(log.Fields{"policy_id": policyDocument.ID})
+	logPolicy.Debugf("Processing policy...")
+	for _, statement := range policyDocument.Statements {
+		// "Effect": "Deny" ?
+		if statement.Effect == "Deny" {
+			// -  "condition" (1/2): { "Bool"  ?
+			for conditionOperator, rawJSON := range statement.Condition {
+				if conditionOperator == "Bool" {
+					denyUnsecureTransport := false
+					conditionKeyValue := &map[string]string{}
+					_ = json.Unmarshal(rawJSON, conditionKeyValue)
+					if value, ok := (*conditionKeyValue)["aws:SecureTransport"]; ok {
+						boolValue, _ := strconv.ParseBool(value)
+						//- condition (2/2) { "aws:SecureTransport": true ?
+						if !boolValue {
+							logPolicy.Debug("aws:SecureTransport is enforced.")
+							denyUnsecureTransport = true
+						}
+					}
+
+					// -  "Action": "*"  or  "Action": "s3:*"  ?
+					s3ActionsCovered := false
+					for _, action := range statement.Action {
+						if action == "*" || action == "s3:*" {
+							logPolicy.Debug("s3ActionsCovered is true")
+							s3ActionsCovered = true
+						}
+					}
+
+					// -  "Principal": "*"  or "Principal": { "AWS": "*" } ?
+					principalCovered := false
+					p := string(statement.Principal)
+					if p == "*" || p == "\"*\"" || p == "'*'" {
+						principalCovered = true
+					} else {
+						var principal map[string]string
+						_ = json.Unmarshal(statement.Principal, &principal)
+						for key, value := range principal {
+							if key == "AWS" && value == "*" {
+								logPolicy.Debug("principalCovered is true")
+								principalCovered = true
+							}
+						}
+					}
+
+					// -  "Resource":  "Resource":"<bucket arn>/*" + "Resource":"<bucket arn>" ?
+					resourceBucket := false
+					resourceBucketContent := false
+					arn := "arn:aws:s3:::" + bucketName
+					for _, r := range statement.Resource {
+						if strings.HasSuffix(r, "*") && !resourceBucketContent {
+							resourceBucketContent = (arn + "/*") == r
+						} else if !resourceBucket {
+							resourceBucket = arn == r
+						}
+					}
+					bucketResourcesCovered := resourceBucket && resourceBucketContent
+					logPolicy.Debugf("bucketResourcesCovered = %v", bucketResourcesCovered)
+
+					policyDenyHTTP = denyUnsecureTransport && s3ActionsCovered &&
+						principalCovered && bucketResourcesCovered
+					logPolicy.Debugf("policyDenyHTTP = %v", policyDenyHTTP)
+				}
+			}
+		}
+	}
+	return policyDenyHTTP
+}
+
+func evaluateEncryption(bucketReport BucketState, level EncryptionLevel) Finding {
+	if !bucketReport.ServerSideEncryptionEnabled {
+		return newFinding(ControlEncryption, false, "", "No server side encryption found")
+	}
+
+	message := "Server side encryption is enabled"
+	if bucketReport.CustomerManagedKey {
+		message = "Server side encryption is enabled with customer managed key"
+	}
+
+	kms := strings.HasPrefix(bucketReport.EncryptionAlgorithm, "aws:kms")
+	switch {
+	case level == EncryptionSSEKMS && !kms:
+		return newFinding(ControlEncryption, false, "", message+", but KMS encryption is required")
+	case level == EncryptionCMK && !bucketReport.CustomerManagedKey:
+		return newFinding(ControlEncryption, false, "", message+", but a customer managed key is required")
+	}
+	return newFinding(ControlEncryption, true, message, "")
+}
 
-func New() *BucketAuditor {
-	return &BucketAuditor{}
+// Report collects the state of the bucket from AWS and evaluates the controls against it.
+func (auditor *BucketAuditor) Report(bucket aws.Bucket) BucketReport {
+	return auditor.Evaluate(Collect(bucket, len(auditor.rules) != 0))
 }
 
-func (auditor *BucketAuditor) Report(bucketName string, accountID string, region string) BucketReport {
+// Collect gathers the configuration of the bucket from AWS; the bucket's tags are only requested
+// if they were not already fetched to filter the buckets. As no control evaluates the ACL, it is
+// only requested if withACL is set, e.g. for custom rules.
+func Collect(bucket aws.Bucket, withACL bool) BucketState {
+	bucketName, accountID, region := bucket.Name, bucket.AccountID, bucket.Region
 	logBucket := log.WithFields(log.Fields{
 		"bucket_name": bucketName,
 	})
 
-	bucketReport := BucketReport{Name: bucketName, AccountID: accountID, Region: region}
+	bucketState := BucketState{Name: bucketName, AccountID: accountID, Region: region, Tags: bucket.Tags}
+
+	s3Client, err := aws.NewS3Client(region)
+	if err != nil {
+		logBucket.Errorf("Error creating S3 client: %v", err)
+		return bucketState
+	}
 
-	cfg, _ := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
-	s3Client := s3.NewFromConfig(cfg)
+	if bucketState.Tags == nil {
+		bucketState.Tags, err = aws.GetBucketTags(s3Client, bucket)
+		if err != nil {
+			logBucket.Warnf("Error getting tags, tag based profiles and requirements do not apply: %v", err)
+			bucketState.MarkUnavailable("Tags could not be read", ControlRequiredTags)
+		}
+	}
 
-	bucketReport.VersioningEnabled = false
-	input := &s3.GetBucketVersioningInput{Bucket: &bucketName, ExpectedBucketOwner: &accountID}
+	bucketState.VersioningEnabled = false
+	input := &s3.GetBucketVersioningInput{Bucket: &bucketName, ExpectedBucketOwner: aws.ExpectedBucketOwner(accountID)}
 
 	versioningOutput, err := s3Client.GetBucketVersioning(context.TODO(), input)
 	if err != nil {
 		logBucket.Debugf("Error getting versioning status for bucket %s: %v", bucketName, err)
+		bucketState.markReadError(err, "", ControlVersioning, ControlMFADelete)
 	} else {
 		versioningStatus := versioningOutput.Status
 		logBucket.Debugf("Versioning status: %#v", versioningStatus)
 		if versioningStatus == "Enabled" {
-			bucketReport.VersioningEnabled = true
+			bucketState.VersioningEnabled = true
 		}
 
 		mfaDelete := versioningOutput.MFADelete
 		logBucket.Debugf("MFA Delete: %#v", mfaDelete)
 		if mfaDelete == "Enabled" {
-			bucketReport.MFADelete = true
+			bucketState.MFADelete = true
 		}
 	}
 
-	encryptionInput := &s3.GetBucketEncryptionInput{Bucket: &bucketName, ExpectedBucketOwner: &accountID}
+	encryptionInput := &s3.GetBucketEncryptionInput{Bucket: &bucketName, ExpectedBucketOwner: aws.ExpectedBucketOwner(accountID)}
 
 	encryptionOutput, err := s3Client.GetBucketEncryption(context.TODO(), encryptionInput)
 	if err != nil {
 		// api error ServerSideEncryptionConfigurationNotFoundError:
 		// The server side encryption configuration was not found
 		logBucket.Debug("Error getting bucket encryption status.")
-		bucketReport.ServerSideEncryptionEnabled = false
-		bucketReport.CustomerManagedKey = false
+		bucketState.markReadError(err, "ServerSideEncryptionConfigurationNotFoundError", ControlEncryption)
+		bucketState.ServerSideEncryptionEnabled = false
+		bucketState.CustomerManagedKey = false
 	} else {
-		bucketReport.ServerSideEncryptionEnabled = true
+		bucketState.ServerSideEncryptionEnabled = true
 
 		for _, rule := range encryptionOutput.ServerSideEncryptionConfiguration.Rules {
-			// 'SSEAlgorithm': 'AES256'|'aws:kms'
+			// 'SSEAlgorithm': 'AES256'|'aws:kms'|'aws:kms:dsse'
+			bucketState.EncryptionAlgorithm = string(rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm)
 			if rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm == "AES256" {
 				logBucket.Info("SSEAlgorithm is 'AES256'")
 			}
@@ -150,23 +403,24 @@ func (auditor *BucketAuditor) Report(bucketName string, accountID string, region
 				rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID != nil {
 				logBucket.Info("SSEAlgorithm is 'aws:kms'")
 				logBucket.Debugf("KMSMasterKeyID: %s", *rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID)
-				bucketReport.CustomerManagedKey = true
+				bucketState.CustomerManagedKey = true
 			}
 			logBucket.Debugf("BucketKeyEnabled: %v", rule.BucketKeyEnabled)
 		}
 	}
 
-	publicAccessBlockInput := &s3.GetPublicAccessBlockInput{Bucket: &bucketName, ExpectedBucketOwner: &accountID}
+	publicAccessBlockInput := &s3.GetPublicAccessBlockInput{Bucket: &bucketName, ExpectedBucketOwner: aws.ExpectedBucketOwner(accountID)}
 
 	publicAccessBlockOutput, err := s3Client.GetPublicAccessBlock(context.TODO(), publicAccessBlockInput)
 	if err != nil {
 		logBucket.Debug("Error getting public access block info.")
+		bucketState.markReadError(err, "NoSuchPublicAccessBlockConfiguration", ControlBlockPublicAccess)
 	} else {
 		conf := publicAccessBlockOutput.PublicAccessBlockConfiguration
-		bucketReport.BlockPublicAccess.BlockPublicAcls = *conf.BlockPublicAcls
-		bucketReport.BlockPublicAccess.BlockPublicPolicy = *conf.BlockPublicPolicy
-		bucketReport.BlockPublicAccess.IgnorePublicAcls = *conf.IgnorePublicAcls
-		bucketReport.BlockPublicAccess.RestrictPublicBuckets = *conf.RestrictPublicBuckets
+		bucketState.BlockPublicAccess.BlockPublicAcls = *conf.BlockPublicAcls
+		bucketState.BlockPublicAccess.BlockPublicPolicy = *conf.BlockPublicPolicy
+		bucketState.BlockPublicAccess.IgnorePublicAcls = *conf.IgnorePublicAcls
+		bucketState.BlockPublicAccess.RestrictPublicBuckets = *conf.RestrictPublicBuckets
 
 	}
 
@@ -175,89 +429,97 @@ func (auditor *BucketAuditor) Report(bucketName string, accountID string, region
 	// https://docs.fugue.co/FG_R00100.html
 	// { "Version":"2012-10-17",  "statement":
 	//	    [{"Sid":"AWSCloudTrailAclCheck20150319","Effect":"Allow","Principal":{"Service":"cloud
-	bucketReport.PolicyDenyHTTP = false
-
-	bucketPolicyInput := &s3.GetBucketPolicyInput{Bucket: &bucketName, ExpectedBucketOwner: &accountID}
+	bucketPolicyInput := &s3.GetBucketPolicyInput{Bucket: &bucketName, ExpectedBucketOwner: aws.ExpectedBucketOwner(accountID)}
 	bucketPolicyOutput, err := s3Client.GetBucketPolicy(context.TODO(), bucketPolicyInput)
 	if err != nil {
+		bucketState.markReadError(err, "NoSuchBucketPolicy", PolicyControls()...)
 		var ae smithy.APIError
 		if errors.As(err, &ae) {
 			logBucket.Debugf("%s:  %s", ae.ErrorCode(), ae.ErrorMessage())
 		}
-	} else {
-		var policyDocument policyDocument
-		err = json.Unmarshal([]byte(*bucketPolicyOutput.Policy), &policyDocument)
-		if err != nil {
-			logBucket.Errorf("Error unmarshalling json %v", err)
-		}
-		logPolicy := logBucket.WithFields(log.Fields{"policy_id": policyDocument.ID})
-		logPolicy.Debugf("Processing policy...")
-		for _, statement := range policyDocument.Statements {
-			// "Effect": "Deny" ?
-			if statement.Effect == "Deny" {
-				// -  "condition" (1/2): { "Bool"  ?
-				for conditionOperator, rawJSON := range statement.Condition {
-					if conditionOperator == "Bool" {
-						denyUnsecureTransport := false
-						conditionKeyValue := &map[string]string{}
-						_ = json.Unmarshal(rawJSON, conditionKeyValue)
-						if value, ok := (*conditionKeyValue)["aws:SecureTransport"]; ok {
-							boolValue, _ := strconv.ParseBool(value)
-							//- condition (2/2) { "aws:SecureTransport": true ?
-							if !boolValue {
-								logPolicy.Debug("aws:SecureTransport is enforced.")
-								denyUnsecureTransport = true
-							}
-						}
+	} else if bucketPolicyOutput.Policy != nil {
+		bucketState.Policy = *bucketPolicyOutput.Policy
+	}
 
-						// -  "Action": "*"  or  "Action": "s3:*"  ?
-						s3ActionsCovered := false
-						for _, action := range statement.Action {
-							if action == "*" || action == "s3:*" {
-								logPolicy.Debug("s3ActionsCovered is true")
-								s3ActionsCovered = true
-							}
-						}
+	collectObjectLock(s3Client, &bucketState, logBucket)
+	collectLifecycle(s3Client, &bucketState, logBucket)
+	collectReplication(s3Client, &bucketState, logBucket)
+	collectWebsite(s3Client, &bucketState, logBucket)
+	collectCORS(s3Client, &bucketState, logBucket)
+	collectNotifications(s3Client, &bucketState, logBucket)
+	if withACL {
+		collectACL(s3Client, &bucketState, logBucket)
+	}
 
-						// -  "Principal": "*"  or "Principal": { "AWS": "*" } ?
-						principalCovered := false
-						p := string(statement.Principal)
-						if p == "*" || p == "\"*\"" || p == "'*'" {
-							principalCovered = true
-						} else {
-							var principal map[string]string
-							_ = json.Unmarshal(statement.Principal, &principal)
-							for key, value := range principal {
-								if key == "AWS" && value == "*" {
-									logPolicy.Debug("principalCovered is true")
-									principalCovered = true
-								}
-							}
-						}
+	// done
+	return bucketState
+}
 
-						// -  "Resource":  "Resource":"<bucket arn>/*" + "Resource":"<bucket arn>" ?
-						resourceBucket := false
-						resourceBucketContent := false
-						arn := "arn:aws:s3:::" + bucketName
-						for _, r := range statement.Resource {
-							if strings.HasSuffix(r, "*") && !resourceBucketContent {
-								resourceBucketContent = (arn + "/*") == r
-							} else if !resourceBucket {
-								resourceBucket = arn == r
-							}
-						}
-						bucketResourcesCovered := resourceBucket && resourceBucketContent
-						logPolicy.Debugf("bucketResourcesCovered = %v", bucketResourcesCovered)
+// Evaluate evaluates all controls against the state of a bucket, e.g. collected by Collect or
+// from an offline source.
+func (auditor *BucketAuditor) Evaluate(bucketState BucketState) BucketReport {
+	logBucket := log.WithFields(log.Fields{
+		"bucket_name": bucketState.Name,
+	})
 
-						bucketReport.PolicyDenyHTTP = denyUnsecureTransport && s3ActionsCovered &&
-							principalCovered && bucketResourcesCovered
-						logPolicy.Debugf("policyDenyHTTP = %v", bucketReport.PolicyDenyHTTP)
-					}
-				}
+	bucketReport := &BucketReport{BucketState: bucketState}
+
+	profile := auditor.profile(bucketReport.Name, bucketReport.Tags)
+	var encryptionLevel EncryptionLevel
+	if profile != nil {
+		bucketReport.Profile = profile.Name
+		encryptionLevel = profile.Encryption
+		logBucket.Debugf("Evaluating under profile '%s'", profile.Name)
+	}
+
+	objectLockRequirement := auditor.objectLockRequirement(bucketReport.Name, bucketReport.Tags, profile)
+	bucketReport.ObjectLock.Required = objectLockRequirement != nil
+	replicationRequirement := auditor.replicationRequirement(bucketReport.Name, bucketReport.Tags, profile)
+	bucketReport.Replication.Required = replicationRequirement != nil
+
+	bpa := bucketReport.BlockPublicAccess
+	bpaFinding := newFinding(ControlBlockPublicAccess, bucketReport.FullyBlocksPublicAccess(),
+		"All 'Block public access' settings are enabled", "Not all 'Block public access' settings are enabled")
+	bpaFinding.Checks = []Check{
+		{Name: "Block Public ACLs", Passed: bpa.BlockPublicAcls},
+		{Name: "Block Public Policy", Passed: bpa.BlockPublicPolicy},
+		{Name: "Ignore Public ACLs", Passed: bpa.IgnorePublicAcls},
+		{Name: "Restrict Public Buckets", Passed: bpa.RestrictPublicBuckets},
+	}
+
+	bucketReport.Findings = []Finding{
+		newFinding(ControlVersioning, bucketReport.VersioningEnabled,
+			"S3 bucket has versioning enabled", "Versioning is not enabled"),
+		newFinding(ControlMFADelete, bucketReport.MFADelete,
+			"MFA delete is enabled", "MFA delete is not enabled"),
+		evaluateEncryption(bucketReport.BucketState, encryptionLevel),
+		bpaFinding,
+		evaluateObjectLock(bucketReport.ObjectLock, objectLockRequirement),
+		evaluateLifecycle(bucketReport.Lifecycle, bucketReport.VersioningEnabled),
+		evaluateReplication(bucketReport.Replication, bucketReport.AccountID, bucketReport.Region, replicationRequirement),
+		evaluateWebsite(bucketReport.Website, bucketReport.FullyBlocksPublicAccess()),
+		evaluateCORS(bucketReport.CORS, bucketReport.FullyBlocksPublicAccess()),
+		evaluateNotifications(bucketReport.Notification, bucketReport.AccountID),
+		evaluateTagPolicy(bucketReport.Tags, auditor.tagPolicy),
+	}
+	bucketReport.Findings = append(bucketReport.Findings, evaluatePolicy(bucketReport)...)
+	bucketReport.Findings = append(bucketReport.Findings, evaluateRules(auditor.rules, bucketState)...)
+	bucketReport.applyUnavailable()
+	bucketReport.applyResources()
+	bucketReport.setDefaultSeverities()
+	bucketReport.overrideSeverities(auditor.severities)
+
+	if profile != nil || len(auditor.controls) != 0 {
+		var findings []Finding
+		for _, f := range bucketReport.Findings {
+			if profile.Includes(f.Control) && (len(auditor.controls) == 0 || slices.Contains(auditor.controls, f.Control)) {
+				findings = append(findings, f)
 			}
 		}
+		bucketReport.Findings = findings
 	}
-
-	// done
-	return bucketReport
+	bucketReport.applySuppressions(auditor.suppressions, time.Now())
+	bucketReport.applyBenchmark(auditor.benchmark)
+	bucketReport.applyFramework(auditor.framework)
+	return *bucketReport
 }
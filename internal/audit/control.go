@@ -0,0 +1,260 @@
+package audit
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/aws/smithy-go"
+)
+
+// ControlID identifies a single check that is evaluated against a bucket.
+type ControlID string
+
+const (
+	ControlEncryption         ControlID = "encryption"
+	ControlDenyHTTP           ControlID = "deny-http"
+	ControlMFADelete          ControlID = "mfa-delete"
+	ControlBlockPublicAccess  ControlID = "block-public-access"
+	ControlVersioning         ControlID = "versioning"
+	ControlObjectLock         ControlID = "object-lock"
+	ControlLifecycle          ControlID = "lifecycle"
+	ControlReplication        ControlID = "replication"
+	ControlWebsite            ControlID = "website"
+	ControlCORS               ControlID = "cors"
+	ControlNotification       ControlID = "notification"
+	ControlRequiredTags       ControlID = "required-tags"
+	ControlPolicyTLSVersion   ControlID = "policy-tls-version"
+	ControlPolicyPublic       ControlID = "policy-public-access"
+	ControlPolicyCrossAccount ControlID = "policy-cross-account"
+)
+
+// Severity ranks how critical a failed control is.
+type Severity string
+
+const (
+	SeverityLow      Severity = "LOW"
+	SeverityMedium   Severity = "MEDIUM"
+	SeverityHigh     Severity = "HIGH"
+	SeverityCritical Severity = "CRITICAL"
+)
+
+var severities = []Severity{SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+// IsValid reports whether s is one of the known severities.
+func (s Severity) IsValid() bool {
+	return slices.Contains(severities, s)
+}
+
+// Raise returns the next higher severity; critical stays critical.
+func (s Severity) Raise() Severity {
+	for i, severity := range severities[:len(severities)-1] {
+		if s == severity {
+			return severities[i+1]
+		}
+	}
+	return s
+}
+
+// Lower returns the next lower severity; low stays low.
+func (s Severity) Lower() Severity {
+	for i, severity := range severities[1:] {
+		if s == severity {
+			return severities[i]
+		}
+	}
+	return s
+}
+
+// Control describes a check; the CIS benchmark items referencing it depend on the Benchmark version.
+type Control struct {
+	ID       ControlID
+	Title    string
+	Severity Severity // default severity of a failed control
+}
+
+var controls = []Control{
+	{ControlEncryption, "Ensure all S3 buckets employ encryption-at-rest", SeverityHigh},
+	{ControlDenyHTTP, "Ensure S3 Bucket Policy is set to deny HTTP requests", SeverityMedium},
+	{ControlMFADelete, "Ensure MFA Delete is enabled on S3 buckets", SeverityLow},
+	{ControlBlockPublicAccess, "Ensure that S3 Buckets are configured with 'Block public access'", SeverityHigh},
+	{ControlVersioning, "S3 bucket versioning enabled", SeverityLow},
+	{ControlPolicyTLSVersion, "S3 bucket policy denies requests using TLS versions below 1.2", SeverityLow},
+	{ControlPolicyPublic, "S3 bucket policy does not grant public access", SeverityCritical},
+	{ControlPolicyCrossAccount, "S3 bucket policy does not grant access to other accounts", SeverityMedium},
+	{ControlObjectLock, "S3 Object Lock enabled with required default retention", SeverityMedium},
+	{ControlLifecycle, "S3 lifecycle rules expire noncurrent versions and abort incomplete uploads", SeverityLow},
+	{ControlReplication, "S3 replication configured as required", SeverityMedium},
+	{ControlWebsite, "S3 static website hosting disabled", SeverityMedium},
+	{ControlCORS, "S3 CORS configuration does not allow any origin or write methods", SeverityMedium},
+	{ControlNotification, "S3 event notifications are only sent to destinations in the bucket's account", SeverityMedium},
+	{ControlRequiredTags, "S3 bucket has all required tags with allowed values", SeverityLow},
+}
+
+// Controls returns all known controls in the order they are evaluated.
+func Controls() []Control {
+	return controls
+}
+
+// LookupControl returns the control with the given id.
+func LookupControl(id ControlID) (Control, bool) {
+	for _, c := range controls {
+		if c.ID == id {
+			return c, true
+		}
+	}
+	return Control{}, false
+}
+
+// ControlSeverity returns the default severity of the control with the given id.
+func ControlSeverity(id ControlID) Severity {
+	control, _ := LookupControl(id)
+	return control.Severity
+}
+
+// Status is the outcome of evaluating a control.
+type Status string
+
+const (
+	StatusPass          Status = "PASS"
+	StatusFail          Status = "FAIL"
+	StatusNotApplicable Status = "N/A"
+)
+
+// Check is a single sub-item of a control, e.g. one of the four 'Block public access' settings.
+type Check struct {
+	Name     string `json:"name"`
+	Passed   bool   `json:"passed"`
+	Location string `json:"location,omitempty"` // JSON pointer into the evaluated document, e.g. '/Statement/0'
+}
+
+// Finding is the result of evaluating one control against one bucket.
+type Finding struct {
+	Control  ControlID `json:"control"`
+	Status   Status    `json:"status"`
+	Severity Severity  `json:"severity"`
+	Message  string    `json:"message"`
+	Checks   []Check   `json:"checks,omitempty"`
+	Resource string    `json:"resource,omitempty"` // source of the evaluated configuration, e.g. a Terraform resource address
+	Title    string    `json:"title,omitempty"`    // title of a custom rule
+
+	Benchmark    *BenchmarkItem      `json:"benchmark,omitempty"`    // CIS benchmark item of the control, if any
+	Requirements []string            `json:"requirements,omitempty"` // requirement IDs of the selected framework
+	Suppression  *FindingSuppression `json:"suppression,omitempty"`  // set if a failed finding is matched by a suppression
+}
+
+// Suppressed reports whether the finding failed, but is accepted by a suppression that has not expired.
+func (f Finding) Suppressed() bool {
+	return f.Status == StatusFail && f.Suppression != nil && !f.Suppression.Expired
+}
+
+func newFinding(control ControlID, passed bool, passMessage string, failMessage string) Finding {
+	if passed {
+		return Finding{Control: control, Status: StatusPass, Message: passMessage}
+	}
+	return Finding{Control: control, Status: StatusFail, Message: failMessage}
+}
+
+// setDefaultSeverities sets the control's default severity on all findings without an explicit one.
+func (r *BucketReport) setDefaultSeverities() {
+	for i, f := range r.Findings {
+		if f.Severity != "" {
+			continue
+		}
+		r.Findings[i].Severity = ControlSeverity(f.Control)
+	}
+}
+
+// overrideSeverities sets the configured severity on all findings of the controls.
+func (r *BucketReport) overrideSeverities(severities map[ControlID]Severity) {
+	for i, f := range r.Findings {
+		if severity, ok := severities[f.Control]; ok {
+			r.Findings[i].Severity = severity
+		}
+	}
+}
+
+// FullyBlocksPublicAccess reports whether all four 'Block public access' settings are enabled.
+func (r *BucketState) FullyBlocksPublicAccess() bool {
+	bpa := r.BlockPublicAccess
+	return bpa.BlockPublicAcls && bpa.BlockPublicPolicy && bpa.IgnorePublicAcls && bpa.RestrictPublicBuckets
+}
+
+// notSupportedErrorCodes are returned by S3 compatible backends for APIs they do not implement.
+var notSupportedErrorCodes = map[string]bool{
+	"NotImplemented":  true,
+	"XNotImplemented": true,
+	"NotSupported":    true,
+}
+
+func isNotSupported(err error) bool {
+	var ae smithy.APIError
+	return errors.As(err, &ae) && notSupportedErrorCodes[ae.ErrorCode()]
+}
+
+// markReadError records the controls as not applicable if err is anything but the error code signalling
+// that the configuration does not exist, e.g. access denied or throttling; evaluating them against
+// a configuration that could not be read would report, and remediate, it as missing.
+func (r *BucketState) markReadError(err error, absentCode string, ids ...ControlID) {
+	var ae smithy.APIError
+	switch {
+	case errors.As(err, &ae) && ae.ErrorCode() == absentCode:
+	case isNotSupported(err):
+		r.MarkUnavailable("Not supported by the S3 endpoint", ids...)
+	case errors.As(err, &ae):
+		r.MarkUnavailable("Configuration could not be read: "+ae.ErrorCode(), ids...)
+	default:
+		r.MarkUnavailable("Configuration could not be read: "+err.Error(), ids...)
+	}
+}
+
+// MarkUnavailable records that the controls cannot be evaluated for the given reason;
+// they are reported as not applicable.
+func (r *BucketState) MarkUnavailable(reason string, ids ...ControlID) {
+	if r.Unavailable == nil {
+		r.Unavailable = map[ControlID]string{}
+	}
+	for _, id := range ids {
+		r.Unavailable[id] = reason
+	}
+}
+
+// SetResource records the resource, e.g. of an infrastructure as code template, that defines the configuration
+// evaluated by the controls; findings of other controls refer to the bucket's Resource.
+func (r *BucketState) SetResource(resource string, ids ...ControlID) {
+	if r.Resources == nil {
+		r.Resources = map[ControlID]string{}
+	}
+	for _, id := range ids {
+		r.Resources[id] = resource
+	}
+}
+
+// applyResources sets the resource defining the evaluated configuration on the findings.
+func (r *BucketReport) applyResources() {
+	for i, f := range r.Findings {
+		if resource, ok := r.Resources[f.Control]; ok {
+			r.Findings[i].Resource = resource
+		} else {
+			r.Findings[i].Resource = r.Resource
+		}
+	}
+}
+
+// applyUnavailable replaces the findings of controls that cannot be evaluated with not applicable ones.
+func (r *BucketReport) applyUnavailable() {
+	for i, f := range r.Findings {
+		if reason, ok := r.Unavailable[f.Control]; ok {
+			r.Findings[i] = Finding{Control: f.Control, Status: StatusNotApplicable, Message: reason}
+		}
+	}
+}
+
+// Finding returns the finding for the given control, if it was evaluated.
+func (r *BucketReport) Finding(id ControlID) (Finding, bool) {
+	for _, f := range r.Findings {
+		if f.Control == id {
+			return f, true
+		}
+	}
+	return Finding{}, false
+}
@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/rollwagen/s3-cisbench/internal/aws"
+	log "github.com/sirupsen/logrus"
+)
+
+// CORSRuleReport holds a single CORS rule and whether it is considered risky.
+type CORSRuleReport struct {
+	ID             string   `json:"id,omitempty"`
+	AllowedOrigins []string `json:"allowedOrigins"`
+	AllowedMethods []string `json:"allowedMethods"`
+	WildcardOrigin bool     `json:"wildcardOrigin"` // any origin, '*'
+	// SubdomainWildcard is set for origins with a wildcard restricted to a domain, e.g. 'https://*.example.com'.
+	SubdomainWildcard bool `json:"subdomainWildcard"`
+	WriteMethods      bool `json:"writeMethods"`
+}
+
+// CORSReport holds the CORS configuration of a bucket.
+type CORSReport struct {
+	Rules []CORSRuleReport `json:"rules,omitempty"`
+}
+
+// NewCORSRuleReport returns the report of a CORS rule, flagging wildcard origins and write methods.
+func NewCORSRuleReport(id *string, origins []string, methods []string) CORSRuleReport {
+	rule := CORSRuleReport{AllowedOrigins: origins, AllowedMethods: methods}
+	if id != nil {
+		rule.ID = *id
+	}
+	for _, origin := range origins {
+		switch {
+		case origin == "*":
+			rule.WildcardOrigin = true
+		case strings.Contains(origin, "*"):
+			rule.SubdomainWildcard = true
+		}
+	}
+	for _, method := range methods {
+		switch strings.ToUpper(method) {
+		case http.MethodPut, http.MethodPost, http.MethodDelete:
+			rule.WriteMethods = true
+		}
+	}
+	return rule
+}
+
+func collectCORS(s3Client *s3.Client, bucketState *BucketState, logBucket *log.Entry) {
+	bucketName := bucketState.Name
+	input := &s3.GetBucketCorsInput{Bucket: &bucketName, ExpectedBucketOwner: aws.ExpectedBucketOwner(bucketState.AccountID)}
+
+	output, err := s3Client.GetBucketCors(context.TODO(), input)
+	if err != nil {
+		bucketState.markReadError(err, "NoSuchCORSConfiguration", ControlCORS)
+		// api error NoSuchCORSConfiguration: The CORS configuration does not exist
+		var ae smithy.APIError
+		if errors.As(err, &ae) {
+			logBucket.Debugf("%s:  %s", ae.ErrorCode(), ae.ErrorMessage())
+		}
+	} else {
+		for _, rule := range output.CORSRules {
+			ruleReport := NewCORSRuleReport(rule.ID, rule.AllowedOrigins, rule.AllowedMethods)
+			logBucket.Debugf("CORS rule: %+v", ruleReport)
+			bucketState.CORS.Rules = append(bucketState.CORS.Rules, ruleReport)
+		}
+	}
+}
+
+func evaluateCORS(cors CORSReport, blocksPublicAccess bool) Finding {
+	finding := Finding{Control: ControlCORS}
+	if len(cors.Rules) == 0 {
+		finding.Status = StatusPass
+		finding.Message = "No CORS configuration found"
+		return finding
+	}
+
+	wildcardOrigin := Check{Name: "No rule allows any origin"}
+	subdomainWildcard := Check{Name: "No rule allows origins by subdomain wildcard"}
+	writeMethods := Check{Name: "No rule allows PUT, POST or DELETE"}
+	wildcardOrigin.Passed, subdomainWildcard.Passed, writeMethods.Passed = true, true, true
+	for _, rule := range cors.Rules {
+		if rule.WildcardOrigin {
+			wildcardOrigin.Passed = false
+		}
+		if rule.SubdomainWildcard {
+			subdomainWildcard.Passed = false
+		}
+		if rule.WriteMethods {
+			writeMethods.Passed = false
+		}
+	}
+	finding.Checks = []Check{wildcardOrigin, subdomainWildcard, writeMethods}
+
+	severity := ControlSeverity(ControlCORS)
+	switch {
+	case !wildcardOrigin.Passed || !writeMethods.Passed:
+		finding.Message = fmt.Sprintf("CORS configuration with %d rule(s) allows any origin or write methods", len(cors.Rules))
+	case !subdomainWildcard.Passed:
+		// any subdomain of a domain is less exposed than any origin
+		severity = severity.Lower()
+		finding.Message = fmt.Sprintf("CORS configuration with %d rule(s) allows origins by subdomain wildcard", len(cors.Rules))
+	default:
+		finding.Status = StatusPass
+		finding.Message = fmt.Sprintf("CORS configuration with %d rule(s) restricts origins and methods", len(cors.Rules))
+		return finding
+	}
+
+	finding.Status = StatusFail
+	if !blocksPublicAccess {
+		severity = severity.Raise()
+		finding.Message += "; bucket is not fully covered by 'Block public access'"
+	}
+	if severity != ControlSeverity(ControlCORS) {
+		finding.Severity = severity
+	}
+	return finding
+}
@@ -0,0 +1,229 @@
+package audit
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Framework is a compliance framework besides the CIS benchmark that results can be reported against.
+type Framework string
+
+const (
+	FrameworkFSBP      Framework = "fsbp"        // AWS Foundational Security Best Practices
+	FrameworkNIST80053 Framework = "nist-800-53" // NIST SP 800-53 Rev. 5
+	FrameworkPCIDSS    Framework = "pci-dss"     // PCI DSS v4.0
+	FrameworkISO27001  Framework = "iso-27001"   // ISO/IEC 27001:2022 Annex A
+)
+
+// Requirement is a requirement of a framework relevant for S3 buckets and the controls evaluating it;
+// requirements without controls cannot be evaluated by this tool.
+type Requirement struct {
+	ID       string      `json:"id"`
+	Title    string      `json:"title"`
+	Controls []ControlID `json:"controls,omitempty"`
+}
+
+type frameworkDefinition struct {
+	framework    Framework
+	name         string
+	label        string // short name prefixing requirement IDs, e.g. 'FSBP'
+	requirements []Requirement
+}
+
+// frameworkDefinitions map the controls to the S3 related requirements of each framework. Except for FSBP,
+// which defines S3 specific controls, the mappings are indicative and follow the AWS Security Hub mappings.
+// Requirements for all buckets are not mapped to controls that only check them if configured, e.g. replication
+// and Object Lock are not applicable unless required and encryption passes SSE-S3 unless a profile requires KMS.
+var frameworkDefinitions = []frameworkDefinition{
+	{FrameworkFSBP, "AWS Foundational Security Best Practices", "FSBP", []Requirement{
+		{"S3.1", "S3 general purpose buckets should have block public access settings enabled (account level)", nil},
+		{"S3.2", "S3 general purpose buckets should block public read access", []ControlID{ControlBlockPublicAccess, ControlPolicyPublic}},
+		{"S3.3", "S3 general purpose buckets should block public write access", []ControlID{ControlBlockPublicAccess, ControlPolicyPublic}},
+		{"S3.5", "S3 general purpose buckets should require requests to use SSL", []ControlID{ControlDenyHTTP}},
+		{"S3.6", "S3 general purpose bucket policies should restrict access to other AWS accounts", []ControlID{ControlPolicyCrossAccount}},
+		{"S3.7", "S3 general purpose buckets should use cross-Region replication", nil},
+		{"S3.8", "S3 general purpose buckets should block public access", []ControlID{ControlBlockPublicAccess}},
+		{"S3.9", "S3 general purpose buckets should have server access logging enabled", nil},
+		{"S3.10", "S3 general purpose buckets with versioning enabled should have Lifecycle configurations", []ControlID{ControlLifecycle}},
+		{"S3.11", "S3 general purpose buckets should have event notifications enabled", nil},
+		{"S3.12", "ACLs should not be used to manage user access to S3 general purpose buckets", nil},
+		{"S3.13", "S3 general purpose buckets should have Lifecycle configurations", []ControlID{ControlLifecycle}},
+		{"S3.14", "S3 general purpose buckets should have versioning enabled", []ControlID{ControlVersioning}},
+		{"S3.15", "S3 general purpose buckets should have Object Lock enabled", nil},
+		{"S3.17", "S3 general purpose buckets should be encrypted at rest with AWS KMS keys", nil},
+		{"S3.19", "S3 access points should have block public access settings enabled", nil},
+		{"S3.20", "S3 general purpose buckets should have MFA delete enabled", []ControlID{ControlMFADelete}},
+		{"S3.22", "S3 general purpose buckets should log object-level write events", nil},
+		{"S3.23", "S3 general purpose buckets should log object-level read events", nil},
+		{"S3.24", "S3 Multi-Region Access Points should have block public access settings enabled", nil},
+	}},
+	{FrameworkNIST80053, "NIST SP 800-53 Rev. 5", "NIST", []Requirement{
+		{"AC-3", "Access Enforcement", []ControlID{ControlBlockPublicAccess, ControlPolicyPublic, ControlPolicyCrossAccount}},
+		{"AC-4", "Information Flow Enforcement", []ControlID{ControlBlockPublicAccess, ControlPolicyCrossAccount, ControlNotification}},
+		{"AC-6", "Least Privilege", []ControlID{ControlPolicyPublic, ControlPolicyCrossAccount}},
+		{"AC-21", "Information Sharing", []ControlID{ControlBlockPublicAccess, ControlPolicyPublic}},
+		{"AU-2", "Event Logging", nil},
+		{"AU-12", "Audit Record Generation", nil},
+		{"CM-8", "System Component Inventory", []ControlID{ControlRequiredTags}},
+		{"CP-9", "System Backup", []ControlID{ControlVersioning, ControlReplication, ControlObjectLock}},
+		{"CP-10", "System Recovery and Reconstitution", []ControlID{ControlVersioning, ControlReplication}},
+		{"IA-2", "Identification and Authentication (Organizational Users)", []ControlID{ControlMFADelete}},
+		{"SC-7", "Boundary Protection", []ControlID{ControlBlockPublicAccess, ControlWebsite, ControlCORS}},
+		{"SC-8", "Transmission Confidentiality and Integrity", []ControlID{ControlDenyHTTP, ControlPolicyTLSVersion}},
+		{"SC-13", "Cryptographic Protection", []ControlID{ControlEncryption, ControlPolicyTLSVersion}},
+		{"SC-28", "Protection of Information at Rest", []ControlID{ControlEncryption}},
+		{"SI-12", "Information Management and Retention", []ControlID{ControlLifecycle, ControlObjectLock}},
+	}},
+	{FrameworkPCIDSS, "PCI DSS v4.0", "PCI", []Requirement{
+		{"1.3.1", "Inbound traffic to the CDE is restricted", []ControlID{ControlBlockPublicAccess, ControlPolicyPublic}},
+		{"1.4.4", "System components that store cardholder data are not directly accessible from untrusted networks",
+			[]ControlID{ControlBlockPublicAccess, ControlPolicyPublic, ControlWebsite}},
+		{"3.2.1", "Account data storage is kept to a minimum through retention and disposal policies", []ControlID{ControlLifecycle}},
+		{"3.5.1", "PAN is rendered unreadable anywhere it is stored", []ControlID{ControlEncryption}},
+		{"4.2.1", "Strong cryptography safeguards PAN during transmission", []ControlID{ControlDenyHTTP, ControlPolicyTLSVersion}},
+		{"7.2.1", "An access control model is defined", []ControlID{ControlPolicyCrossAccount, ControlPolicyPublic}},
+		{"10.2.1", "Audit logs are enabled and active for all system components and cardholder data", nil},
+		{"10.5.1", "Audit log history is retained for at least 12 months", nil},
+		{"12.5.1", "An inventory of system components in scope for PCI DSS is maintained", []ControlID{ControlRequiredTags}},
+	}},
+	{FrameworkISO27001, "ISO/IEC 27001:2022 Annex A", "ISO", []Requirement{
+		{"A.5.9", "Inventory of information and other associated assets", []ControlID{ControlRequiredTags}},
+		{"A.5.15", "Access control", []ControlID{ControlBlockPublicAccess, ControlPolicyPublic, ControlPolicyCrossAccount}},
+		{"A.5.33", "Protection of records", []ControlID{ControlVersioning, ControlObjectLock}},
+		{"A.8.3", "Information access restriction",
+			[]ControlID{ControlBlockPublicAccess, ControlPolicyPublic, ControlPolicyCrossAccount, ControlWebsite, ControlCORS}},
+		{"A.8.5", "Secure authentication", []ControlID{ControlMFADelete}},
+		{"A.8.10", "Information deletion", []ControlID{ControlLifecycle}},
+		{"A.8.12", "Data leakage prevention", []ControlID{ControlBlockPublicAccess, ControlPolicyPublic, ControlNotification}},
+		{"A.8.13", "Information backup", []ControlID{ControlVersioning, ControlReplication, ControlObjectLock}},
+		{"A.8.14", "Redundancy of information processing facilities", nil},
+		{"A.8.15", "Logging", nil},
+		{"A.8.20", "Networks security", []ControlID{ControlDenyHTTP}},
+		{"A.8.24", "Use of cryptography", []ControlID{ControlEncryption, ControlDenyHTTP, ControlPolicyTLSVersion}},
+	}},
+}
+
+// Frameworks returns the supported frameworks.
+func Frameworks() []Framework {
+	var frameworks []Framework
+	for _, d := range frameworkDefinitions {
+		frameworks = append(frameworks, d.framework)
+	}
+	return frameworks
+}
+
+func (f Framework) definition() (frameworkDefinition, bool) {
+	for _, d := range frameworkDefinitions {
+		if d.framework == f {
+			return d, true
+		}
+	}
+	return frameworkDefinition{}, false
+}
+
+// ValidateFramework returns an error if the framework is not supported; empty selects none.
+func ValidateFramework(f Framework) error {
+	if _, ok := f.definition(); ok || f == "" {
+		return nil
+	}
+	var names []string
+	for _, framework := range Frameworks() {
+		names = append(names, string(framework))
+	}
+	return fmt.Errorf("invalid framework '%s': expected one of %s", f, strings.Join(names, ", "))
+}
+
+// Name returns the framework's full name, e.g. 'NIST SP 800-53 Rev. 5'.
+func (f Framework) Name() string {
+	d, _ := f.definition()
+	return d.name
+}
+
+// Label returns the framework's short name, e.g. 'FSBP'.
+func (f Framework) Label() string {
+	d, _ := f.definition()
+	return d.label
+}
+
+// Requirements returns the S3 related requirements of the framework.
+func (f Framework) Requirements() []Requirement {
+	d, _ := f.definition()
+	return d.requirements
+}
+
+// RequirementIDs returns the IDs of the framework's requirements the control evaluates.
+func (f Framework) RequirementIDs(id ControlID) []string {
+	var ids []string
+	for _, r := range f.Requirements() {
+		if slices.Contains(r.Controls, id) {
+			ids = append(ids, r.ID)
+		}
+	}
+	return ids
+}
+
+// Mappings returns the requirement IDs of all frameworks the control evaluates.
+func (c Control) Mappings() map[Framework][]string {
+	mappings := map[Framework][]string{}
+	for _, f := range Frameworks() {
+		if ids := f.RequirementIDs(c.ID); len(ids) != 0 {
+			mappings[f] = ids
+		}
+	}
+	return mappings
+}
+
+// applyFramework labels the findings with the IDs of the framework's requirements their control evaluates.
+func (r *BucketReport) applyFramework(f Framework) {
+	if f == "" {
+		return
+	}
+	for i, finding := range r.Findings {
+		r.Findings[i].Requirements = f.RequirementIDs(finding.Control)
+	}
+}
+
+// RequirementResult aggregates the findings of the controls evaluating a requirement over all buckets.
+type RequirementResult struct {
+	Requirement
+	Evaluated     bool `json:"evaluated"` // false if no control evaluates the requirement
+	Passed        int  `json:"passed"`
+	Failed        int  `json:"failed"` // failed findings, including suppressed ones
+	NotApplicable int  `json:"notApplicable"`
+}
+
+// FrameworkResults groups the findings of the reports by the framework's requirements.
+func FrameworkResults(f Framework, reports []BucketReport) []RequirementResult {
+	var results []RequirementResult
+	for _, requirement := range f.Requirements() {
+		result := RequirementResult{Requirement: requirement, Evaluated: len(requirement.Controls) != 0}
+		for _, report := range reports {
+			for _, finding := range report.Findings {
+				if !slices.Contains(requirement.Controls, finding.Control) {
+					continue
+				}
+				switch finding.Status {
+				case StatusPass:
+					result.Passed++
+				case StatusFail:
+					result.Failed++
+				default:
+					result.NotApplicable++
+				}
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// Coverage returns the number of the framework's requirements the controls evaluate and of all requirements.
+func (f Framework) Coverage() (evaluated int, total int) {
+	for _, r := range f.Requirements() {
+		if len(r.Controls) != 0 {
+			evaluated++
+		}
+	}
+	return evaluated, len(f.Requirements())
+}
@@ -0,0 +1,134 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/rollwagen/s3-cisbench/internal/aws"
+	log "github.com/sirupsen/logrus"
+)
+
+// LifecycleRuleReport holds the relevant parts of a single lifecycle rule.
+type LifecycleRuleReport struct {
+	ID                                 string   `json:"id,omitempty"`
+	Enabled                            bool     `json:"enabled"`
+	NoncurrentVersionExpirationDays    int32    `json:"noncurrentVersionExpirationDays,omitempty"`
+	AbortIncompleteMultipartUploadDays int32    `json:"abortIncompleteMultipartUploadDays,omitempty"`
+	Transitions                        []string `json:"transitions,omitempty"`
+	NoncurrentVersionTransitions       []string `json:"noncurrentVersionTransitions,omitempty"`
+	hasNoncurrentVersionExpiration     bool
+	hasAbortIncompleteMultipartUpload  bool
+}
+
+// LifecycleReport holds the lifecycle configuration of a bucket.
+type LifecycleReport struct {
+	Rules                          []LifecycleRuleReport `json:"rules,omitempty"`
+	NoncurrentVersionExpiration    bool                  `json:"noncurrentVersionExpiration"`
+	AbortIncompleteMultipartUpload bool                  `json:"abortIncompleteMultipartUpload"`
+}
+
+func collectLifecycle(s3Client *s3.Client, bucketState *BucketState, logBucket *log.Entry) {
+	bucketName := bucketState.Name
+	input := &s3.GetBucketLifecycleConfigurationInput{Bucket: &bucketName, ExpectedBucketOwner: aws.ExpectedBucketOwner(bucketState.AccountID)}
+
+	output, err := s3Client.GetBucketLifecycleConfiguration(context.TODO(), input)
+	if err != nil {
+		bucketState.markReadError(err, "NoSuchLifecycleConfiguration", ControlLifecycle)
+		// api error NoSuchLifecycleConfiguration: The lifecycle configuration does not exist
+		var ae smithy.APIError
+		if errors.As(err, &ae) {
+			logBucket.Debugf("%s:  %s", ae.ErrorCode(), ae.ErrorMessage())
+		}
+	} else {
+		for _, rule := range output.Rules {
+			ruleReport := newLifecycleRuleReport(rule)
+			logBucket.Debugf("Lifecycle rule: %+v", ruleReport)
+			bucketState.Lifecycle.Rules = append(bucketState.Lifecycle.Rules, ruleReport)
+			if !ruleReport.Enabled {
+				continue
+			}
+			if ruleReport.hasNoncurrentVersionExpiration {
+				bucketState.Lifecycle.NoncurrentVersionExpiration = true
+			}
+			if ruleReport.hasAbortIncompleteMultipartUpload {
+				bucketState.Lifecycle.AbortIncompleteMultipartUpload = true
+			}
+		}
+	}
+}
+
+func newLifecycleRuleReport(rule types.LifecycleRule) LifecycleRuleReport {
+	ruleReport := LifecycleRuleReport{Enabled: rule.Status == types.ExpirationStatusEnabled}
+	if rule.ID != nil {
+		ruleReport.ID = *rule.ID
+	}
+
+	if e := rule.NoncurrentVersionExpiration; e != nil {
+		ruleReport.hasNoncurrentVersionExpiration = true
+		if e.NoncurrentDays != nil {
+			ruleReport.NoncurrentVersionExpirationDays = *e.NoncurrentDays
+		}
+	}
+
+	if a := rule.AbortIncompleteMultipartUpload; a != nil {
+		ruleReport.hasAbortIncompleteMultipartUpload = true
+		if a.DaysAfterInitiation != nil {
+			ruleReport.AbortIncompleteMultipartUploadDays = *a.DaysAfterInitiation
+		}
+	}
+
+	for _, t := range rule.Transitions {
+		switch {
+		case t.Days != nil:
+			ruleReport.Transitions = append(ruleReport.Transitions, fmt.Sprintf("%s after %d days", t.StorageClass, *t.Days))
+		case t.Date != nil:
+			ruleReport.Transitions = append(ruleReport.Transitions, fmt.Sprintf("%s on %s", t.StorageClass, t.Date.Format("2006-01-02")))
+		}
+	}
+
+	for _, t := range rule.NoncurrentVersionTransitions {
+		if t.NoncurrentDays != nil {
+			ruleReport.NoncurrentVersionTransitions = append(ruleReport.NoncurrentVersionTransitions,
+				fmt.Sprintf("%s after %d days", t.StorageClass, *t.NoncurrentDays))
+		}
+	}
+
+	return ruleReport
+}
+
+func evaluateLifecycle(lifecycle LifecycleReport, versioningEnabled bool) Finding {
+	finding := Finding{Control: ControlLifecycle}
+
+	abort := Check{Name: "Abort incomplete multipart uploads", Passed: lifecycle.AbortIncompleteMultipartUpload}
+	finding.Checks = []Check{abort}
+
+	noncurrent := Check{Name: "Expire noncurrent versions", Passed: lifecycle.NoncurrentVersionExpiration}
+	if versioningEnabled {
+		finding.Checks = append(finding.Checks, noncurrent)
+	}
+
+	var missing []string
+	for _, check := range finding.Checks {
+		if !check.Passed {
+			missing = append(missing, strings.ToLower(check.Name))
+		}
+	}
+
+	if len(missing) != 0 {
+		finding.Status = StatusFail
+		finding.Message = "No lifecycle rule to " + strings.Join(missing, " or ")
+		return finding
+	}
+
+	finding.Status = StatusPass
+	finding.Message = fmt.Sprintf("Lifecycle configuration with %d rule(s) covers incomplete uploads", len(lifecycle.Rules))
+	if versioningEnabled {
+		finding.Message += " and noncurrent versions"
+	}
+	return finding
+}
@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/rollwagen/s3-cisbench/internal/aws"
+	log "github.com/sirupsen/logrus"
+)
+
+// NotificationDestination is a single SNS topic, SQS queue or Lambda function receiving bucket events.
+type NotificationDestination struct {
+	Type         string   `json:"type"` // sns, sqs or lambda
+	ARN          string   `json:"arn"`
+	AccountID    string   `json:"accountId"`
+	Events       []string `json:"events"`
+	CrossAccount bool     `json:"crossAccount"`
+}
+
+// NotificationReport holds the event notification configuration of a bucket.
+type NotificationReport struct {
+	EventBridgeEnabled bool                      `json:"eventBridgeEnabled"`
+	Destinations       []NotificationDestination `json:"destinations,omitempty"`
+}
+
+func newNotificationDestination(destinationType string, destinationARN *string, events []types.Event, accountID string) NotificationDestination {
+	var eventNames []string
+	for _, event := range events {
+		eventNames = append(eventNames, string(event))
+	}
+	var destination string
+	if destinationARN != nil {
+		destination = *destinationARN
+	}
+	return NewNotificationDestination(destinationType, destination, eventNames, accountID)
+}
+
+// NewNotificationDestination returns a destination of a bucket owned by accountID, resolving the destination account from its ARN.
+func NewNotificationDestination(destinationType string, destinationARN string, events []string, accountID string) NotificationDestination {
+	destination := NotificationDestination{Type: destinationType, ARN: destinationARN, Events: events}
+	if parsed, err := arn.Parse(destinationARN); err == nil {
+		destination.AccountID = parsed.AccountID
+	}
+	destination.CrossAccount = destination.AccountID != "" && destination.AccountID != accountID
+	return destination
+}
+
+func collectNotifications(s3Client *s3.Client, bucketState *BucketState, logBucket *log.Entry) {
+	bucketName := bucketState.Name
+	accountID := bucketState.AccountID
+	input := &s3.GetBucketNotificationConfigurationInput{Bucket: &bucketName, ExpectedBucketOwner: aws.ExpectedBucketOwner(accountID)}
+
+	output, err := s3Client.GetBucketNotificationConfiguration(context.TODO(), input)
+	if err != nil {
+		bucketState.markReadError(err, "", ControlNotification)
+		var ae smithy.APIError
+		if errors.As(err, &ae) {
+			logBucket.Debugf("%s:  %s", ae.ErrorCode(), ae.ErrorMessage())
+		}
+	} else {
+		notification := &bucketState.Notification
+		notification.EventBridgeEnabled = output.EventBridgeConfiguration != nil
+		for _, c := range output.TopicConfigurations {
+			notification.Destinations = append(notification.Destinations, newNotificationDestination("sns", c.TopicArn, c.Events, accountID))
+		}
+		for _, c := range output.QueueConfigurations {
+			notification.Destinations = append(notification.Destinations, newNotificationDestination("sqs", c.QueueArn, c.Events, accountID))
+		}
+		for _, c := range output.LambdaFunctionConfigurations {
+			notification.Destinations = append(notification.Destinations, newNotificationDestination("lambda", c.LambdaFunctionArn, c.Events, accountID))
+		}
+		logBucket.Debugf("Notifications: %+v", *notification)
+	}
+}
+
+func evaluateNotifications(notification NotificationReport, accountID string) Finding {
+	finding := Finding{Control: ControlNotification}
+
+	var crossAccount []string
+	for _, d := range notification.Destinations {
+		finding.Checks = append(finding.Checks, Check{Name: d.ARN, Passed: !d.CrossAccount})
+		if d.CrossAccount {
+			crossAccount = append(crossAccount, d.ARN)
+		}
+	}
+
+	switch {
+	case accountID == "" && len(crossAccount) != 0:
+		// every destination with an account counts as cross-account if the bucket account is unknown
+		finding.Status = StatusNotApplicable
+		finding.Message = "Bucket account unknown; event notifications are sent to " + strings.Join(crossAccount, ", ")
+	case len(crossAccount) != 0:
+		finding.Status = StatusFail
+		finding.Message = fmt.Sprintf("Event notifications are sent to other accounts: %s", strings.Join(crossAccount, ", "))
+	case len(notification.Destinations) == 0:
+		finding.Status = StatusPass
+		finding.Message = "No event notification destinations found"
+	default:
+		finding.Status = StatusPass
+		finding.Message = fmt.Sprintf("All %d event notification destination(s) are in the bucket's account",
+			len(notification.Destinations))
+	}
+
+	if notification.EventBridgeEnabled {
+		finding.Message += "; events are also sent to Amazon EventBridge"
+	}
+	return finding
+}
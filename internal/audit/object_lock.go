@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/rollwagen/s3-cisbench/internal/aws"
+	log "github.com/sirupsen/logrus"
+)
+
+const daysPerYear = 365
+
+// ObjectLockRequirement defines the minimum Object Lock configuration for the buckets matched by Selector.
+type ObjectLockRequirement struct {
+	Selector         BucketSelector `yaml:"selector"`
+	Mode             string         `yaml:"mode"` // GOVERNANCE or COMPLIANCE; COMPLIANCE also satisfies GOVERNANCE
+	MinRetentionDays int32          `yaml:"minRetentionDays"`
+}
+
+// ObjectLockReport holds the Object Lock configuration of a bucket.
+type ObjectLockReport struct {
+	Enabled       bool   `json:"enabled"`
+	Mode          string `json:"mode,omitempty"`
+	RetentionDays int32  `json:"retentionDays,omitempty"`
+	Required      bool   `json:"required"`
+}
+
+// ValidateObjectLockMode returns an error if mode is not a valid default retention mode.
+func ValidateObjectLockMode(mode string) error {
+	switch types.ObjectLockRetentionMode(mode) {
+	case "", types.ObjectLockRetentionModeGovernance, types.ObjectLockRetentionModeCompliance:
+		return nil
+	}
+	return fmt.Errorf("invalid object lock mode '%s': allowed are GOVERNANCE or COMPLIANCE", mode)
+}
+
+// objectLockRequirement returns the requirement of the bucket's profile or else the first matching requirement.
+func (auditor *BucketAuditor) objectLockRequirement(name string, tags map[string]string, profile *Profile) *ObjectLockRequirement {
+	if profile != nil && profile.ObjectLock != nil {
+		return profile.ObjectLock
+	}
+	for i, req := range auditor.objectLockRequirements {
+		if req.Selector.Matches(name, tags) {
+			return &auditor.objectLockRequirements[i]
+		}
+	}
+	return nil
+}
+
+func collectObjectLock(s3Client *s3.Client, bucketState *BucketState, logBucket *log.Entry) {
+	bucketName := bucketState.Name
+	input := &s3.GetObjectLockConfigurationInput{Bucket: &bucketName, ExpectedBucketOwner: aws.ExpectedBucketOwner(bucketState.AccountID)}
+
+	output, err := s3Client.GetObjectLockConfiguration(context.TODO(), input)
+	if err != nil {
+		bucketState.markReadError(err, "ObjectLockConfigurationNotFoundError", ControlObjectLock)
+		// api error ObjectLockConfigurationNotFoundError: Object Lock configuration does not exist for this bucket
+		var ae smithy.APIError
+		if errors.As(err, &ae) {
+			logBucket.Debugf("%s:  %s", ae.ErrorCode(), ae.ErrorMessage())
+		}
+	} else if conf := output.ObjectLockConfiguration; conf != nil {
+		bucketState.ObjectLock.Enabled = conf.ObjectLockEnabled == types.ObjectLockEnabledEnabled
+		if conf.Rule != nil && conf.Rule.DefaultRetention != nil {
+			retention := conf.Rule.DefaultRetention
+			bucketState.ObjectLock.Mode = string(retention.Mode)
+			if retention.Days != nil {
+				bucketState.ObjectLock.RetentionDays = *retention.Days
+			}
+			if retention.Years != nil {
+				bucketState.ObjectLock.RetentionDays = *retention.Years * daysPerYear
+			}
+		}
+		logBucket.Debugf("Object Lock: %+v", bucketState.ObjectLock)
+	}
+}
+
+func evaluateObjectLock(lock ObjectLockReport, req *ObjectLockRequirement) Finding {
+	finding := Finding{Control: ControlObjectLock}
+
+	if req == nil {
+		finding.Status = StatusNotApplicable
+		if lock.Enabled {
+			finding.Message = "Object Lock is enabled; not required for this bucket"
+		} else {
+			finding.Message = "Object Lock is not required for this bucket"
+		}
+		return finding
+	}
+
+	enabled := Check{Name: "Object Lock enabled", Passed: lock.Enabled}
+	mode := Check{Name: "Default retention mode set", Passed: lock.Mode != ""}
+	if req.Mode != "" {
+		mode.Name = "Default retention mode " + req.Mode
+		mode.Passed = lock.Mode == req.Mode || lock.Mode == string(types.ObjectLockRetentionModeCompliance)
+	}
+	retention := Check{
+		Name:   fmt.Sprintf("Default retention of at least %d days", req.MinRetentionDays),
+		Passed: lock.RetentionDays >= req.MinRetentionDays,
+	}
+	finding.Checks = []Check{enabled, mode, retention}
+
+	switch {
+	case !lock.Enabled:
+		finding.Status = StatusFail
+		finding.Message = "Object Lock is required but not enabled"
+	case !mode.Passed || !retention.Passed:
+		finding.Status = StatusFail
+		finding.Message = fmt.Sprintf("Default retention %s/%d days does not meet required %s/%d days",
+			lock.Mode, lock.RetentionDays, req.Mode, req.MinRetentionDays)
+	default:
+		finding.Status = StatusPass
+		finding.Message = fmt.Sprintf("Object Lock is enabled with default retention %s/%d days", lock.Mode, lock.RetentionDays)
+	}
+	return finding
+}
@@ -0,0 +1,330 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	minTLSVersion       = 1.2 // minimum TLS version a bucket policy should enforce
+	maxDeniedTLSVersion = 1.1 // highest TLS version a bucket policy should deny
+)
+
+// restrictingConditionKeys limit an allow statement with a public principal to known callers,
+// as considered by S3 when deciding whether a bucket policy is public.
+var restrictingConditionKeys = map[string]bool{
+	"aws:sourceip":              true,
+	"aws:sourcevpc":             true,
+	"aws:sourcevpce":            true,
+	"aws:sourcearn":             true,
+	"aws:sourceaccount":         true,
+	"aws:sourceowner":           true,
+	"aws:sourceorgid":           true,
+	"aws:sourceorgpaths":        true,
+	"aws:principalarn":          true,
+	"aws:principalaccount":      true,
+	"aws:principalorgid":        true,
+	"aws:principalorgpaths":     true,
+	"aws:userid":                true,
+	"s3:dataaccesspointaccount": true,
+	"s3:dataaccesspointarn":     true,
+}
+
+var accountIDPattern = regexp.MustCompile(`^\d{12}$`)
+
+// PolicyControls returns the controls evaluated against the bucket policy.
+func PolicyControls() []ControlID {
+	return []ControlID{ControlDenyHTTP, ControlPolicyTLSVersion, ControlPolicyPublic, ControlPolicyCrossAccount}
+}
+
+// policyStatement is a statement of a policy document and its JSON pointer, e.g. '/Statement/0'.
+type policyStatement struct {
+	pointer string
+	values  map[string]any
+}
+
+// pointer appends reference tokens to a JSON pointer (RFC 6901).
+func pointer(base string, tokens ...any) string {
+	for _, token := range tokens {
+		escaped := strings.ReplaceAll(fmt.Sprint(token), "~", "~0")
+		base += "/" + strings.ReplaceAll(escaped, "/", "~1")
+	}
+	return base
+}
+
+// parsePolicyStatements returns the statements of a policy document, which may hold a single
+// statement object or a list of statements.
+func parsePolicyStatements(policy string) ([]policyStatement, error) {
+	var document map[string]any
+	if err := json.Unmarshal([]byte(policy), &document); err != nil {
+		return nil, fmt.Errorf("invalid policy document: %v", err)
+	}
+
+	var statements []policyStatement
+	switch s := document["Statement"].(type) {
+	case map[string]any:
+		statements = append(statements, policyStatement{pointer("", "Statement"), s})
+	case []any:
+		for i, item := range s {
+			values, ok := item.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("invalid policy document: statement %s is not an object", pointer("", "Statement", i))
+			}
+			statements = append(statements, policyStatement{pointer("", "Statement", i), values})
+		}
+	default:
+		return nil, fmt.Errorf("invalid policy document: no statements")
+	}
+	return statements, nil
+}
+
+// stringValues converts a policy element that is a string or a list of strings into a list.
+func stringValues(v any) []string {
+	switch value := v.(type) {
+	case string:
+		return []string{value}
+	case []any:
+		var values []string
+		for _, item := range value {
+			values = append(values, fmt.Sprint(item))
+		}
+		return values
+	case nil:
+		return nil
+	default:
+		return []string{fmt.Sprint(value)}
+	}
+}
+
+func (s policyStatement) effect() string {
+	effect, _ := s.values["Effect"].(string)
+	return effect
+}
+
+// name identifies the statement in messages by its Sid, if set.
+func (s policyStatement) name() string {
+	if sid, ok := s.values["Sid"].(string); ok && sid != "" {
+		return fmt.Sprintf("Statement '%s'", sid)
+	}
+	return "Statement " + s.pointer
+}
+
+// principal is an AWS principal of a statement and its JSON pointer.
+type principal struct {
+	location string
+	value    string
+}
+
+// awsPrincipals returns the AWS principals of the statement; principal '*' is returned as AWS principal.
+func (s policyStatement) awsPrincipals() []principal {
+	var principals []principal
+	switch p := s.values["Principal"].(type) {
+	case string:
+		principals = append(principals, principal{pointer(s.pointer, "Principal"), p})
+	case map[string]any:
+		switch aws := p["AWS"].(type) {
+		case string:
+			principals = append(principals, principal{pointer(s.pointer, "Principal", "AWS"), aws})
+		case []any:
+			for i, item := range aws {
+				principals = append(principals, principal{pointer(s.pointer, "Principal", "AWS", i), fmt.Sprint(item)})
+			}
+		}
+	}
+	return principals
+}
+
+// isPublic reports whether the statement applies to everyone, i.e. has principal '*' or uses NotPrincipal.
+func (s policyStatement) isPublic() (bool, string) {
+	if _, ok := s.values["NotPrincipal"]; ok {
+		return true, pointer(s.pointer, "NotPrincipal")
+	}
+	for _, p := range s.awsPrincipals() {
+		if p.value == "*" {
+			return true, p.location
+		}
+	}
+	return false, ""
+}
+
+// conditionKeys returns all condition keys, in lower case, of the statement.
+func (s policyStatement) conditionKeys() []string {
+	var keys []string
+	conditions, _ := s.values["Condition"].(map[string]any)
+	for _, operands := range conditions {
+		entries, _ := operands.(map[string]any)
+		for key := range entries {
+			keys = append(keys, strings.ToLower(key))
+		}
+	}
+	return keys
+}
+
+// deniesHTTP evaluates the statement as the only statement of a policy with the deny HTTP control.
+func (s policyStatement) deniesHTTP(bucketName string, logBucket *log.Entry) bool {
+	policy, err := json.Marshal(map[string]any{"Statement": []any{s.values}})
+	return err == nil && PolicyDeniesHTTP(bucketName, string(policy), logBucket)
+}
+
+// tlsVersionLocation returns the location of the condition if the statement denies requests using
+// TLS versions below minTLSVersion for everyone.
+func (s policyStatement) tlsVersionLocation() (string, bool) {
+	if s.effect() != "Deny" {
+		return "", false
+	}
+	if public, _ := s.isPublic(); !public {
+		return "", false
+	}
+	conditions, _ := s.values["Condition"].(map[string]any)
+	for operator, operands := range conditions {
+		if operator != "NumericLessThan" && operator != "NumericLessThanEquals" {
+			continue
+		}
+		entries, _ := operands.(map[string]any)
+		for key, value := range entries {
+			if !strings.EqualFold(key, "s3:TlsVersion") {
+				continue
+			}
+			for _, v := range stringValues(value) {
+				version, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					continue
+				}
+				// '<= 1.1' denies the same versions as '< 1.2'
+				if (operator == "NumericLessThan" && version >= minTLSVersion) || (operator == "NumericLessThanEquals" && version >= maxDeniedTLSVersion) {
+					return pointer(s.pointer, "Condition", operator, key), true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// principalAccount returns the account of an AWS principal given as account ID or ARN.
+func principalAccount(principal string) string {
+	if accountIDPattern.MatchString(principal) {
+		return principal
+	}
+	if parsed, err := arn.Parse(principal); err == nil {
+		return parsed.AccountID
+	}
+	return ""
+}
+
+// evaluatePolicy evaluates the policy controls against the bucket policy of the report.
+func evaluatePolicy(bucketReport *BucketReport) []Finding {
+	logBucket := log.WithFields(log.Fields{"bucket_name": bucketReport.Name})
+
+	tlsFinding := Finding{Control: ControlPolicyTLSVersion, Status: StatusFail,
+		Message: "No bucket policy to deny requests using TLS versions below 1.2 found"}
+	publicFinding := Finding{Control: ControlPolicyPublic, Status: StatusPass, Message: "Bucket policy does not grant public access"}
+	crossAccountFinding := Finding{Control: ControlPolicyCrossAccount, Status: StatusPass,
+		Message: "Bucket policy does not grant access to other accounts"}
+
+	if bucketReport.Policy == "" {
+		publicFinding.Message = "No bucket policy found"
+		crossAccountFinding.Message = "No bucket policy found"
+		return []Finding{
+			newFinding(ControlDenyHTTP, bucketReport.PolicyDenyHTTP,
+				"Bucket policy to deny HTTP requests is present", "No Bucket policy to deny HTTP requests found"),
+			tlsFinding, publicFinding, crossAccountFinding,
+		}
+	}
+
+	statements, err := parsePolicyStatements(bucketReport.Policy)
+	if err != nil {
+		logBucket.Errorf("Error parsing bucket policy: %v", err)
+		var findings []Finding
+		for _, id := range PolicyControls() {
+			findings = append(findings, Finding{Control: id, Status: StatusFail, Message: err.Error()})
+		}
+		return findings
+	}
+
+	bucketReport.PolicyDenyHTTP = false
+	denyHTTPFinding := Finding{Control: ControlDenyHTTP, Status: StatusFail, Message: "No Bucket policy to deny HTTP requests found"}
+	var publicStatements, crossAccountPrincipals []string
+
+	for _, s := range statements {
+		if !bucketReport.PolicyDenyHTTP && s.deniesHTTP(bucketReport.Name, logBucket) {
+			bucketReport.PolicyDenyHTTP = true
+			denyHTTPFinding.Status = StatusPass
+			denyHTTPFinding.Message = "Bucket policy to deny HTTP requests is present"
+			denyHTTPFinding.Checks = append(denyHTTPFinding.Checks, Check{Name: s.name() + " denies HTTP requests", Passed: true, Location: s.pointer})
+		}
+
+		if location, ok := s.tlsVersionLocation(); ok && tlsFinding.Status == StatusFail {
+			tlsFinding.Status = StatusPass
+			tlsFinding.Message = "Bucket policy to deny requests using TLS versions below 1.2 is present"
+			tlsFinding.Checks = append(tlsFinding.Checks, Check{Name: s.name() + " denies TLS versions below 1.2", Passed: true, Location: location})
+		}
+
+		if s.effect() != "Allow" {
+			continue
+		}
+
+		if public, location := s.isPublic(); public {
+			restricted := false
+			for _, key := range s.conditionKeys() {
+				if restrictingConditionKeys[key] {
+					restricted = true
+				}
+			}
+			check := Check{Name: s.name() + " allows everyone", Passed: restricted, Location: location}
+			if restricted {
+				check.Name += " restricted by condition"
+			} else {
+				publicStatements = append(publicStatements, s.name())
+			}
+			publicFinding.Checks = append(publicFinding.Checks, check)
+		}
+
+		for _, p := range s.awsPrincipals() {
+			account := principalAccount(p.value)
+			if account == "" {
+				continue
+			}
+			crossAccount := bucketReport.AccountID == "" || account != bucketReport.AccountID
+			crossAccountFinding.Checks = append(crossAccountFinding.Checks, Check{
+				Name: s.name() + " allows " + p.value, Passed: !crossAccount, Location: p.location,
+			})
+			if crossAccount {
+				crossAccountPrincipals = append(crossAccountPrincipals, p.value)
+			}
+		}
+	}
+
+	if len(publicStatements) != 0 {
+		publicFinding.Status = StatusFail
+		publicFinding.Message = "Bucket policy grants public access: " + strings.Join(publicStatements, ", ")
+	}
+	switch {
+	case bucketReport.AccountID == "" && len(crossAccountPrincipals) != 0:
+		crossAccountFinding = Finding{Control: ControlPolicyCrossAccount, Status: StatusNotApplicable,
+			Message: "Bucket account unknown; bucket policy grants access to " + strings.Join(crossAccountPrincipals, ", "),
+			Checks:  crossAccountFinding.Checks}
+	case len(crossAccountPrincipals) != 0:
+		crossAccountFinding.Status = StatusFail
+		crossAccountFinding.Message = "Bucket policy grants access to other accounts: " + strings.Join(crossAccountPrincipals, ", ")
+	}
+
+	return []Finding{denyHTTPFinding, tlsFinding, publicFinding, crossAccountFinding}
+}
+
+// EvaluatePolicy evaluates only the policy controls against the bucket policy of the state,
+// e.g. to lint a policy document before it is applied.
+func (auditor *BucketAuditor) EvaluatePolicy(bucketState BucketState) BucketReport {
+	bucketReport := &BucketReport{BucketState: bucketState}
+	bucketReport.Findings = evaluatePolicy(bucketReport)
+	bucketReport.applyResources()
+	bucketReport.setDefaultSeverities()
+	bucketReport.applyBenchmark(auditor.benchmark)
+	bucketReport.applyFramework(auditor.framework)
+	return *bucketReport
+}
@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EncryptionLevel is the minimum default encryption a bucket must use.
+type EncryptionLevel string
+
+const (
+	EncryptionSSES3  EncryptionLevel = "sse-s3"  // any server side encryption, e.g. AES256
+	EncryptionSSEKMS EncryptionLevel = "sse-kms" // encryption with a KMS key (aws:kms or aws:kms:dsse)
+	EncryptionCMK    EncryptionLevel = "cmk"     // encryption with a customer managed KMS key
+)
+
+// Profile selects the controls and thresholds applied to the buckets matched by Selector,
+// e.g. all buckets tagged 'data-classification=confidential'.
+type Profile struct {
+	Name        string                  `yaml:"name"`
+	Selector    BucketSelector          `yaml:"selector"`
+	Controls    []ControlID             `yaml:"controls"` // if empty, all controls are evaluated
+	Encryption  EncryptionLevel         `yaml:"encryption"`
+	ObjectLock  *ObjectLockRequirement  `yaml:"objectLock"`
+	Replication *ReplicationRequirement `yaml:"replication"`
+}
+
+// Includes reports whether the control is evaluated under this profile.
+func (p *Profile) Includes(id ControlID) bool {
+	if p == nil || len(p.Controls) == 0 {
+		return true
+	}
+	for _, c := range p.Controls {
+		if c == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate returns an error if the profile references unknown controls or invalid thresholds.
+func (p *Profile) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("profile without name")
+	}
+	for _, id := range p.Controls {
+		if !IsValidControl(id) {
+			return fmt.Errorf("profile '%s': unknown control '%s'", p.Name, id)
+		}
+	}
+	switch p.Encryption {
+	case "", EncryptionSSES3, EncryptionSSEKMS, EncryptionCMK:
+	default:
+		return fmt.Errorf("profile '%s': invalid encryption '%s': allowed are sse-s3, sse-kms or cmk", p.Name, p.Encryption)
+	}
+	if p.ObjectLock != nil {
+		if err := ValidateObjectLockMode(p.ObjectLock.Mode); err != nil {
+			return fmt.Errorf("profile '%s': %v", p.Name, err)
+		}
+	}
+	if err := ValidatePatterns(p.Selector.Names); err != nil {
+		return fmt.Errorf("profile '%s': %v", p.Name, err)
+	}
+	return nil
+}
+
+// LoadProfiles reads and validates profiles from a YAML file with a top level 'profiles' list.
+func LoadProfiles(path string) ([]Profile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Profiles []Profile `yaml:"profiles"`
+	}
+	if err := yaml.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("could not parse profiles file %s: %v", path, err)
+	}
+
+	for i := range file.Profiles {
+		if err := file.Profiles[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return file.Profiles, nil
+}
+
+func (auditor *BucketAuditor) profile(name string, tags map[string]string) *Profile {
+	for i, p := range auditor.profiles {
+		if p.Selector.Matches(name, tags) {
+			return &auditor.profiles[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/rollwagen/s3-cisbench/internal/aws"
+	log "github.com/sirupsen/logrus"
+)
+
+// ReplicationRequirement defines the replication expected for the buckets matched by Selector.
+type ReplicationRequirement struct {
+	Selector                BucketSelector `yaml:"selector"`
+	CrossRegion             bool           `yaml:"crossRegion"`             // destination bucket must be in a different region
+	CrossAccount            bool           `yaml:"crossAccount"`            // destination bucket must be owned by a different account
+	DestinationAccount      string         `yaml:"destinationAccount"`      // if set, destination bucket must be owned by this account
+	ReplicaKMS              bool           `yaml:"replicaKms"`              // replicas must be encrypted with a KMS key
+	DeleteMarkerReplication bool           `yaml:"deleteMarkerReplication"` // delete markers must be replicated
+}
+
+// ReplicationRuleReport holds a single replication rule and its destination.
+type ReplicationRuleReport struct {
+	ID                      string `json:"id,omitempty"`
+	Enabled                 bool   `json:"enabled"`
+	DestinationBucket       string `json:"destinationBucket"`
+	DestinationAccount      string `json:"destinationAccount"`
+	DestinationRegion       string `json:"destinationRegion,omitempty"`
+	ReplicaKMSKeyID         string `json:"replicaKmsKeyId,omitempty"`
+	DeleteMarkerReplication bool   `json:"deleteMarkerReplication"`
+}
+
+// ReplicationReport holds the replication configuration of a bucket.
+type ReplicationReport struct {
+	Role     string                  `json:"role,omitempty"`
+	Rules    []ReplicationRuleReport `json:"rules,omitempty"`
+	Required bool                    `json:"required"`
+}
+
+// replicationRequirement returns the requirement of the bucket's profile or else the first matching requirement.
+func (auditor *BucketAuditor) replicationRequirement(name string, tags map[string]string, profile *Profile) *ReplicationRequirement {
+	if profile != nil && profile.Replication != nil {
+		return profile.Replication
+	}
+	for i, req := range auditor.replicationRequirements {
+		if req.Selector.Matches(name, tags) {
+			return &auditor.replicationRequirements[i]
+		}
+	}
+	return nil
+}
+
+func collectReplication(s3Client *s3.Client, bucketState *BucketState, logBucket *log.Entry) {
+	bucketName := bucketState.Name
+	input := &s3.GetBucketReplicationInput{Bucket: &bucketName, ExpectedBucketOwner: aws.ExpectedBucketOwner(bucketState.AccountID)}
+
+	output, err := s3Client.GetBucketReplication(context.TODO(), input)
+	if err != nil {
+		bucketState.markReadError(err, "ReplicationConfigurationNotFoundError", ControlReplication)
+		// api error ReplicationConfigurationNotFoundError: The replication configuration was not found
+		var ae smithy.APIError
+		if errors.As(err, &ae) {
+			logBucket.Debugf("%s:  %s", ae.ErrorCode(), ae.ErrorMessage())
+		}
+	} else if conf := output.ReplicationConfiguration; conf != nil {
+		if conf.Role != nil {
+			bucketState.Replication.Role = *conf.Role
+		}
+		for _, rule := range conf.Rules {
+			ruleReport := newReplicationRuleReport(rule)
+			if ruleReport.DestinationBucket != "" {
+				region, err := manager.GetBucketRegion(context.TODO(), s3Client, ruleReport.DestinationBucket)
+				if err != nil {
+					logBucket.Debugf("Could not get region of destination bucket %s: %v", ruleReport.DestinationBucket, err)
+				}
+				ruleReport.DestinationRegion = region
+			}
+			logBucket.Debugf("Replication rule: %+v", ruleReport)
+			bucketState.Replication.Rules = append(bucketState.Replication.Rules, ruleReport)
+		}
+	}
+}
+
+func newReplicationRuleReport(rule types.ReplicationRule) ReplicationRuleReport {
+	ruleReport := ReplicationRuleReport{
+		Enabled: rule.Status == types.ReplicationRuleStatusEnabled,
+	}
+	if rule.ID != nil {
+		ruleReport.ID = *rule.ID
+	}
+
+	if d := rule.Destination; d != nil {
+		if d.Bucket != nil {
+			ruleReport.DestinationBucket = strings.TrimPrefix(*d.Bucket, "arn:aws:s3:::")
+		}
+		if d.Account != nil {
+			ruleReport.DestinationAccount = *d.Account
+		}
+		if d.EncryptionConfiguration != nil && d.EncryptionConfiguration.ReplicaKmsKeyID != nil {
+			ruleReport.ReplicaKMSKeyID = *d.EncryptionConfiguration.ReplicaKmsKeyID
+		}
+	}
+
+	if m := rule.DeleteMarkerReplication; m != nil {
+		ruleReport.DeleteMarkerReplication = m.Status == types.DeleteMarkerReplicationStatusEnabled
+	}
+
+	return ruleReport
+}
+
+func evaluateReplication(replication ReplicationReport, accountID string, region string, req *ReplicationRequirement) Finding {
+	finding := Finding{Control: ControlReplication}
+
+	var enabledRules []ReplicationRuleReport
+	for _, rule := range replication.Rules {
+		if rule.Enabled {
+			enabledRules = append(enabledRules, rule)
+		}
+	}
+
+	if req == nil {
+		finding.Status = StatusNotApplicable
+		if len(enabledRules) != 0 {
+			finding.Message = fmt.Sprintf("Replication is configured with %d enabled rule(s); not required for this bucket", len(enabledRules))
+		} else {
+			finding.Message = "Replication is not required for this bucket"
+		}
+		return finding
+	}
+
+	// a requirement is met if a single enabled rule fulfills all expectations
+	ruleMatches := func(matches func(ReplicationRuleReport) bool) bool {
+		for _, rule := range enabledRules {
+			if matches(rule) {
+				return true
+			}
+		}
+		return false
+	}
+	checks := []struct {
+		check   Check
+		enabled bool
+		matches func(ReplicationRuleReport) bool
+	}{
+		{
+			Check{Name: "Replication rule enabled"}, true,
+			func(ReplicationRuleReport) bool { return true },
+		},
+		{
+			Check{Name: "Destination in another region"}, req.CrossRegion,
+			func(r ReplicationRuleReport) bool { return r.DestinationRegion != "" && r.DestinationRegion != region },
+		},
+		{
+			Check{Name: "Destination in another account"}, req.CrossAccount,
+			func(r ReplicationRuleReport) bool {
+				return r.DestinationAccount != "" && r.DestinationAccount != accountID
+			},
+		},
+		{
+			Check{Name: "Destination in account " + req.DestinationAccount}, req.DestinationAccount != "",
+			func(r ReplicationRuleReport) bool { return r.DestinationAccount == req.DestinationAccount },
+		},
+		{
+			Check{Name: "Replicas encrypted with KMS"}, req.ReplicaKMS,
+			func(r ReplicationRuleReport) bool { return r.ReplicaKMSKeyID != "" },
+		},
+		{
+			Check{Name: "Delete marker replication enabled"}, req.DeleteMarkerReplication,
+			func(r ReplicationRuleReport) bool { return r.DeleteMarkerReplication },
+		},
+	}
+
+	var enabledChecks []func(ReplicationRuleReport) bool
+	for _, c := range checks {
+		if !c.enabled {
+			continue
+		}
+		c.check.Passed = ruleMatches(c.matches)
+		finding.Checks = append(finding.Checks, c.check)
+		enabledChecks = append(enabledChecks, c.matches)
+	}
+
+	// e.g. offline audits, where destinations not audited themselves have no known region
+	regionUnknown := req.CrossRegion && (region == "" || ruleMatches(func(r ReplicationRuleReport) bool { return r.DestinationRegion == "" }))
+	// S3 only returns the destination account if the replica owner is overridden
+	accountUnknown := (req.CrossAccount || req.DestinationAccount != "") &&
+		ruleMatches(func(r ReplicationRuleReport) bool { return r.DestinationAccount == "" })
+
+	compliant := ruleMatches(func(r ReplicationRuleReport) bool {
+		for _, matches := range enabledChecks {
+			if !matches(r) {
+				return false
+			}
+		}
+		return true
+	})
+
+	switch {
+	case len(enabledRules) == 0:
+		finding.Status = StatusFail
+		finding.Message = "Replication is required but not configured"
+	case accountID == "" && (req.CrossAccount || req.DestinationAccount != ""):
+		// e.g. with --endpoint-url, or templates audited without --account-id
+		finding.Status = StatusNotApplicable
+		finding.Message = "Bucket account unknown; the destination accounts of replication rules cannot be checked"
+	case !compliant && accountUnknown:
+		finding.Status = StatusNotApplicable
+		finding.Message = "Destination account of replication rules unknown; the destination accounts cannot be checked"
+	case !compliant && regionUnknown:
+		finding.Status = StatusNotApplicable
+		finding.Message = "Destination region of replication rules unknown; cross-region replication cannot be checked"
+	case !compliant:
+		finding.Status = StatusFail
+		finding.Message = "No replication rule meets all expectations"
+	default:
+		finding.Status = StatusPass
+		finding.Message = fmt.Sprintf("Replication is configured with %d enabled rule(s) meeting all expectations", len(enabledRules))
+	}
+	return finding
+}
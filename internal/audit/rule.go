@@ -0,0 +1,280 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/ext"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// CustomControlPrefix prefixes the control IDs of custom rules, e.g. 'custom:bucket-naming'.
+const CustomControlPrefix = "custom:"
+
+var ruleIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// IsCustomControl reports whether the control is a custom rule.
+func IsCustomControl(id ControlID) bool {
+	return strings.HasPrefix(string(id), CustomControlPrefix) && len(id) > len(CustomControlPrefix)
+}
+
+// IsValidControl reports whether the control is a known control or a custom rule.
+func IsValidControl(id ControlID) bool {
+	_, ok := LookupControl(id)
+	return ok || IsCustomControl(id)
+}
+
+// Rule is a custom rule written in CEL and evaluated against the collected state of a bucket,
+// which is available as 'bucket' in the same structure as a BucketState serialized by WriteStates.
+type Rule struct {
+	ID       string   `yaml:"id"`
+	Title    string   `yaml:"title"`
+	Severity Severity `yaml:"severity"` // severity of a failed rule; MEDIUM if not set
+	// When is an optional condition; the rule is not applicable to buckets it does not hold for.
+	When string `yaml:"when"`
+	// Expression returns true if the bucket passes, or the list of violations, which fails the bucket if not empty.
+	Expression string `yaml:"expression"`
+	Message    string `yaml:"message"` // message of a failed finding if the expression returns false
+
+	file       string
+	when       cel.Program
+	expression cel.Program
+}
+
+// Control returns the ID of the rule's findings.
+func (r *Rule) Control() ControlID {
+	return ControlID(CustomControlPrefix + r.ID)
+}
+
+func newRuleEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("bucket", cel.DynType),
+		cel.CrossTypeNumericComparisons(true),
+		cel.OptionalTypes(),
+		ext.Strings(),
+		ext.Lists(),
+		ext.Sets(),
+	)
+}
+
+// compile validates the rule and compiles its expressions.
+func (r *Rule) compile(env *cel.Env) error {
+	if !ruleIDPattern.MatchString(r.ID) {
+		return fmt.Errorf("%s: invalid rule id '%s': expected lower case letters, digits and '-'", r.file, r.ID)
+	}
+	if r.Title == "" {
+		return fmt.Errorf("%s: rule '%s': title is mandatory", r.file, r.ID)
+	}
+	if r.Severity == "" {
+		r.Severity = SeverityMedium
+	}
+	if !r.Severity.IsValid() {
+		return fmt.Errorf("%s: rule '%s': invalid severity '%s'", r.file, r.ID, r.Severity)
+	}
+	if r.Expression == "" {
+		return fmt.Errorf("%s: rule '%s': expression is mandatory", r.file, r.ID)
+	}
+
+	var err error
+	if r.When != "" {
+		if r.when, err = compileExpression(env, r.When); err != nil {
+			return fmt.Errorf("%s: rule '%s': when: %v", r.file, r.ID, err)
+		}
+	}
+	if r.expression, err = compileExpression(env, r.Expression); err != nil {
+		return fmt.Errorf("%s: rule '%s': expression: %v", r.file, r.ID, err)
+	}
+	return nil
+}
+
+func compileExpression(env *cel.Env, expression string) (cel.Program, error) {
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	return env.Program(ast)
+}
+
+// LoadRules reads, validates and compiles the custom rules of all YAML files with a top level 'rules' list
+// in the directory.
+func LoadRules(dir string) ([]Rule, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	env, err := newRuleEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []Rule
+	ids := map[string]string{}
+	for _, path := range files {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var file struct {
+			Rules []Rule `yaml:"rules"`
+		}
+		if err := yaml.Unmarshal(b, &file); err != nil {
+			return nil, fmt.Errorf("could not parse rules file %s: %v", path, err)
+		}
+		for _, rule := range file.Rules {
+			rule.file = path
+			if err := rule.compile(env); err != nil {
+				return nil, err
+			}
+			if other, ok := ids[rule.ID]; ok {
+				return nil, fmt.Errorf("%s: rule '%s' is already defined in %s", path, rule.ID, other)
+			}
+			ids[rule.ID] = path
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// ruleInput returns the collected state of the bucket as rule input.
+func ruleInput(bucketState BucketState) (map[string]any, error) {
+	b, err := json.Marshal(bucketState)
+	if err != nil {
+		return nil, err
+	}
+	input := map[string]any{}
+	if err := json.Unmarshal(b, &input); err != nil {
+		return nil, err
+	}
+	if _, ok := input["tags"]; !ok {
+		input["tags"] = map[string]any{}
+	}
+	// the parsed policy document, so rules need not parse the 'policy' string themselves
+	var policyDocument map[string]any
+	if bucketState.Policy != "" && json.Unmarshal([]byte(bucketState.Policy), &policyDocument) == nil {
+		input["policyDocument"] = policyDocument
+	}
+	return input, nil
+}
+
+// evaluateRules evaluates the custom rules against the bucket.
+func evaluateRules(rules []Rule, bucketState BucketState) []Finding {
+	if len(rules) == 0 {
+		return nil
+	}
+	logBucket := log.WithFields(log.Fields{"bucket_name": bucketState.Name})
+
+	input, err := ruleInput(bucketState)
+	if err != nil {
+		logBucket.Errorf("Error creating input of custom rules: %v", err)
+		return nil
+	}
+	var findings []Finding
+	for i := range rules {
+		findings = append(findings, rules[i].evaluate(map[string]any{"bucket": input}, logBucket))
+	}
+	return findings
+}
+
+func (r *Rule) evaluate(activation map[string]any, logBucket *log.Entry) Finding {
+	finding := Finding{Control: r.Control(), Title: r.Title, Severity: r.Severity}
+	notApplicable := func(message string) Finding {
+		finding.Status, finding.Message = StatusNotApplicable, message
+		return finding
+	}
+
+	if r.when != nil {
+		out, _, err := r.when.Eval(activation)
+		if err != nil {
+			logBucket.Warnf("Error evaluating condition of rule '%s': %v", r.ID, err)
+			return notApplicable("Rule condition could not be evaluated: " + err.Error())
+		}
+		if out != types.True {
+			return notApplicable("Rule does not apply to this bucket")
+		}
+	}
+
+	out, _, err := r.expression.Eval(activation)
+	if err != nil {
+		logBucket.Warnf("Error evaluating rule '%s': %v", r.ID, err)
+		return notApplicable("Rule could not be evaluated: " + err.Error())
+	}
+
+	var violations []string
+	if passed, ok := out.Value().(bool); ok {
+		if !passed {
+			message := r.Message
+			if message == "" {
+				message = r.Title + " is not met"
+			}
+			violations = []string{message}
+		}
+	} else {
+		v, err := out.ConvertToNative(reflect.TypeOf([]string{}))
+		if err != nil {
+			logBucket.Warnf("Rule '%s' returned %s instead of a bool or a list of strings", r.ID, out.Type())
+			return notApplicable(fmt.Sprintf("Rule returned %s instead of a bool or a list of strings", out.Type()))
+		}
+		violations = v.([]string)
+	}
+
+	switch len(violations) {
+	case 0:
+		finding.Status, finding.Message = StatusPass, "No violations found"
+	case 1:
+		finding.Status, finding.Message = StatusFail, violations[0]
+	default:
+		finding.Status, finding.Message = StatusFail, fmt.Sprintf("%d violations found", len(violations))
+		for _, violation := range violations {
+			finding.Checks = append(finding.Checks, Check{Name: violation})
+		}
+	}
+	return finding
+}
+
+// SortControls returns the distinct controls, known ones in the order of Controls() followed by custom rules
+// in alphabetical order.
+func SortControls(ids []ControlID) []ControlID {
+	var sorted, custom []ControlID
+	for _, control := range controls {
+		if slices.Contains(ids, control.ID) {
+			sorted = append(sorted, control.ID)
+		}
+	}
+	for _, id := range ids {
+		if _, ok := LookupControl(id); !ok && !slices.Contains(custom, id) {
+			custom = append(custom, id)
+		}
+	}
+	slices.Sort(custom)
+	return append(sorted, custom...)
+}
+
+// ReportControls returns the controls of the findings of the reports, ordered by SortControls.
+func ReportControls(reports ...BucketReport) []ControlID {
+	var ids []ControlID
+	for _, r := range reports {
+		for _, f := range r.Findings {
+			ids = append(ids, f.Control)
+		}
+	}
+	return SortControls(ids)
+}
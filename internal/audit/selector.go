@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// regexPatternPrefix marks a name pattern as regular expression instead of a glob pattern.
+const regexPatternPrefix = "re:"
+
+// anyTagValue as tag value matches every value of a present tag key.
+const anyTagValue = "*"
+
+// MatchName reports whether name matches the pattern. Patterns are glob patterns as understood
+// by path.Match, e.g. 'backup-*', or regular expressions if prefixed with 're:', e.g. 're:^(dev|test)-'.
+func MatchName(pattern string, name string) bool {
+	if expr, ok := strings.CutPrefix(pattern, regexPatternPrefix); ok {
+		re, err := regexp.Compile(expr)
+		return err == nil && re.MatchString(name)
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// ValidatePatterns returns an error for the first invalid glob or regular expression pattern.
+func ValidatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		var err error
+		if expr, ok := strings.CutPrefix(pattern, regexPatternPrefix); ok {
+			_, err = regexp.Compile(expr)
+		} else {
+			_, err = path.Match(pattern, "")
+		}
+		if err != nil {
+			return fmt.Errorf("invalid bucket name pattern '%s': %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+func matchAnyName(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if MatchName(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchTag(tags map[string]string, key string, value string) bool {
+	v, ok := tags[key]
+	return ok && (value == anyTagValue || v == value)
+}
+
+// BucketSelector matches buckets by name pattern and/or tag values.
+// An empty selector matches every bucket.
+type BucketSelector struct {
+	Names []string          `yaml:"names"` // name patterns, see MatchName
+	Tags  map[string]string `yaml:"tags"`  // all tags must be present with the given value; '*' matches any value
+}
+
+// Matches reports whether a bucket with the given name and tags is selected.
+// Name patterns are OR'ed, tags are AND'ed; both must match if both are set.
+func (s BucketSelector) Matches(name string, tags map[string]string) bool {
+	if len(s.Names) != 0 && !matchAnyName(s.Names, name) {
+		return false
+	}
+
+	for key, value := range s.Tags {
+		if !matchTag(tags, key, value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// BucketFilter selects the buckets to audit.
+type BucketFilter struct {
+	Include     []string          // name patterns; if set, only matching buckets are selected
+	Exclude     []string          // name patterns; matching buckets are never selected
+	Tags        map[string]string // all tags must be present with the given value
+	ExcludeTags map[string]string // buckets with any of these tags are never selected
+}
+
+// NeedsTags reports whether bucket tags are required to evaluate the filter.
+func (f BucketFilter) NeedsTags() bool {
+	return len(f.Tags) != 0 || len(f.ExcludeTags) != 0
+}
+
+// Matches reports whether a bucket with the given name and tags passes the filter.
+func (f BucketFilter) Matches(name string, tags map[string]string) bool {
+	if matchAnyName(f.Exclude, name) {
+		return false
+	}
+	for key, value := range f.ExcludeTags {
+		if matchTag(tags, key, value) {
+			return false
+		}
+	}
+	return BucketSelector{Names: f.Include, Tags: f.Tags}.Matches(name, tags)
+}
+
+// ParseTagFilters converts 'key=value' strings into a tag map.
+func ParseTagFilters(filters []string) (map[string]string, error) {
+	if len(filters) == 0 {
+		return nil, nil
+	}
+
+	tags := map[string]string{}
+	for _, f := range filters {
+		key, value, found := strings.Cut(f, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid tag filter '%s': expected key=value", f)
+		}
+		tags[key] = value
+	}
+	return tags, nil
+}
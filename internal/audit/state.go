@@ -0,0 +1,47 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// StateVersion is the version of the state file format written by WriteStates.
+const StateVersion = 1
+
+// StateFile is the serialized state of buckets, e.g. to evaluate the controls again without querying AWS.
+type StateFile struct {
+	Version     int           `json:"version"`
+	CollectedAt time.Time     `json:"collectedAt"`
+	Buckets     []BucketState `json:"buckets"`
+}
+
+// WriteStates writes the bucket states as JSON state file.
+func WriteStates(path string, states []BucketState, collectedAt time.Time) error {
+	if states == nil {
+		states = []BucketState{}
+	}
+	b, err := json.MarshalIndent(StateFile{Version: StateVersion, CollectedAt: collectedAt, Buckets: states}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// LoadStates reads a state file written by WriteStates.
+func LoadStates(path string) (*StateFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file StateFile
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("could not parse state file %s: %v", path, err)
+	}
+	if file.Version != StateVersion {
+		return nil, fmt.Errorf("state file %s: unsupported version %d, expected %d", path, file.Version, StateVersion)
+	}
+	return &file, nil
+}
@@ -0,0 +1,137 @@
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SuppressionDateFormat is the format of the expiry date of a suppression.
+const SuppressionDateFormat = "2006-01-02"
+
+// Suppression accepts the risk of a failed control for the buckets matched by Bucket,
+// e.g. a bucket that is intentionally public to host a static website, until it expires.
+type Suppression struct {
+	Bucket        string    `yaml:"bucket"`            // name pattern, see MatchName
+	Account       string    `yaml:"account,omitempty"` // if empty, buckets of all accounts are matched
+	Control       ControlID `yaml:"control"`
+	Justification string    `yaml:"justification"`
+	Expires       string    `yaml:"expires"` // last day the suppression applies, e.g. '2025-12-31'
+}
+
+// FindingSuppression marks a failed finding as accepted risk; an expired suppression no longer applies.
+type FindingSuppression struct {
+	Justification string `json:"justification"`
+	Expires       string `json:"expires"`
+	Expired       bool   `json:"expired,omitempty"`
+}
+
+// Validate returns an error if the suppression misses a field or references an unknown control.
+func (s *Suppression) Validate() error {
+	if s.Bucket == "" {
+		return fmt.Errorf("suppression without bucket")
+	}
+	if err := ValidatePatterns([]string{s.Bucket}); err != nil {
+		return err
+	}
+	if !IsValidControl(s.Control) {
+		return fmt.Errorf("suppression of bucket '%s': unknown control '%s'", s.Bucket, s.Control)
+	}
+	if s.Justification == "" {
+		return fmt.Errorf("suppression of control '%s' of bucket '%s': justification is mandatory", s.Control, s.Bucket)
+	}
+	if _, err := time.Parse(SuppressionDateFormat, s.Expires); err != nil {
+		return fmt.Errorf("suppression of control '%s' of bucket '%s': invalid or missing expiry date '%s': expected YYYY-MM-DD",
+			s.Control, s.Bucket, s.Expires)
+	}
+	return nil
+}
+
+// Matches reports whether the suppression applies to the control of the bucket, regardless of its expiry.
+func (s *Suppression) Matches(bucketName string, accountID string, id ControlID) bool {
+	return s.Control == id && (s.Account == "" || s.Account == accountID) && MatchName(s.Bucket, bucketName)
+}
+
+// ExpiredAt reports whether the suppression no longer applies at the given time; it applies
+// until the end of its expiry date.
+func (s *Suppression) ExpiredAt(t time.Time) bool {
+	expires, err := time.Parse(SuppressionDateFormat, s.Expires)
+	return err != nil || !t.UTC().Before(expires.AddDate(0, 0, 1))
+}
+
+// LoadSuppressions reads and validates suppressions from a YAML file with a top level 'suppressions' list.
+func LoadSuppressions(path string) ([]Suppression, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Suppressions []Suppression `yaml:"suppressions"`
+	}
+	if err := yaml.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("could not parse suppressions file %s: %v", path, err)
+	}
+
+	for i := range file.Suppressions {
+		if err := file.Suppressions[i].Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return file.Suppressions, nil
+}
+
+// WriteSuppressions writes the suppressions as YAML file, e.g. as baseline of the current failures.
+func WriteSuppressions(path string, suppressions []Suppression) error {
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(struct {
+		Suppressions []Suppression `yaml:"suppressions"`
+	}{suppressions}); err != nil {
+		return err
+	}
+	_ = encoder.Close()
+	return os.WriteFile(path, b.Bytes(), 0o644)
+}
+
+// Baseline returns a suppression for every failed finding of the reports: the suppressions of already
+// suppressed findings are kept, including expired ones so that they are not renewed, all others are
+// accepted with the justification until the expiry date.
+func Baseline(reports []BucketReport, justification string, expires time.Time) []Suppression {
+	var suppressions []Suppression
+	for _, r := range reports {
+		for _, f := range r.Findings {
+			if f.Status != StatusFail {
+				continue
+			}
+			s := Suppression{Bucket: r.Name, Account: r.AccountID, Control: f.Control,
+				Justification: justification, Expires: expires.Format(SuppressionDateFormat)}
+			if f.Suppression != nil {
+				s.Justification, s.Expires = f.Suppression.Justification, f.Suppression.Expires
+			}
+			suppressions = append(suppressions, s)
+		}
+	}
+	return suppressions
+}
+
+// applySuppressions marks the failed findings matched by a suppression; the first matching suppression applies.
+func (r *BucketReport) applySuppressions(suppressions []Suppression, now time.Time) {
+	for i, f := range r.Findings {
+		if f.Status != StatusFail {
+			continue
+		}
+		for _, s := range suppressions {
+			if s.Matches(r.Name, r.AccountID, f.Control) {
+				r.Findings[i].Suppression = &FindingSuppression{
+					Justification: s.Justification, Expires: s.Expires, Expired: s.ExpiredAt(now),
+				}
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TagPolicy defines the tags every bucket must carry.
+type TagPolicy struct {
+	Required      []string            // tag keys that must be present
+	AllowedValues map[string][]string // if a tag is present, its value must be one of these
+}
+
+// IsEmpty reports whether the policy neither requires tags nor restricts values.
+func (p TagPolicy) IsEmpty() bool {
+	return len(p.Required) == 0 && len(p.AllowedValues) == 0
+}
+
+// ParseAllowedTagValues converts 'key=value1|value2' strings into a map of allowed values.
+func ParseAllowedTagValues(values []string) (map[string][]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	allowed := map[string][]string{}
+	for _, v := range values {
+		key, list, found := strings.Cut(v, "=")
+		if !found || key == "" || list == "" {
+			return nil, fmt.Errorf("invalid allowed tag values '%s': expected key=value1|value2", v)
+		}
+		allowed[key] = append(allowed[key], strings.Split(list, "|")...)
+	}
+	return allowed, nil
+}
+
+func evaluateTagPolicy(tags map[string]string, policy TagPolicy) Finding {
+	finding := Finding{Control: ControlRequiredTags}
+	if policy.IsEmpty() {
+		finding.Status = StatusNotApplicable
+		finding.Message = "No required tags configured"
+		return finding
+	}
+
+	var missing, invalid []string
+	for _, key := range policy.Required {
+		_, ok := tags[key]
+		finding.Checks = append(finding.Checks, Check{Name: "Tag '" + key + "' present", Passed: ok})
+		if !ok {
+			missing = append(missing, key)
+		}
+	}
+
+	keys := make([]string, 0, len(policy.AllowedValues))
+	for key := range policy.AllowedValues {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, ok := tags[key]
+		if !ok {
+			continue
+		}
+		allowed := policy.AllowedValues[key]
+		passed := false
+		for _, a := range allowed {
+			if value == a {
+				passed = true
+			}
+		}
+		finding.Checks = append(finding.Checks, Check{
+			Name:   fmt.Sprintf("Tag '%s' value '%s' in %s", key, value, strings.Join(allowed, "|")),
+			Passed: passed,
+		})
+		if !passed {
+			invalid = append(invalid, key+"="+value)
+		}
+	}
+
+	var problems []string
+	if len(missing) != 0 {
+		problems = append(problems, "missing required tags "+strings.Join(missing, ", "))
+	}
+	if len(invalid) != 0 {
+		problems = append(problems, "tag values not allowed "+strings.Join(invalid, ", "))
+	}
+
+	if len(problems) != 0 {
+		finding.Status = StatusFail
+		finding.Message = "Bucket tags violate policy: " + strings.Join(problems, "; ")
+		return finding
+	}
+	finding.Status = StatusPass
+	finding.Message = "Bucket has all required tags with allowed values"
+	return finding
+}
@@ -0,0 +1,138 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/rollwagen/s3-cisbench/internal/aws"
+	log "github.com/sirupsen/logrus"
+)
+
+// regions that use the legacy 's3-website-<region>' (dash) website endpoint format
+var legacyWebsiteEndpointRegions = map[string]bool{
+	"us-east-1":      true,
+	"us-west-1":      true,
+	"us-west-2":      true,
+	"ap-southeast-1": true,
+	"ap-southeast-2": true,
+	"ap-northeast-1": true,
+	"eu-west-1":      true,
+	"sa-east-1":      true,
+	"us-gov-west-1":  true,
+}
+
+// WebsiteReport holds the static website hosting configuration of a bucket.
+type WebsiteReport struct {
+	Enabled       bool     `json:"enabled"`
+	Endpoint      string   `json:"endpoint,omitempty"`
+	IndexDocument string   `json:"indexDocument,omitempty"`
+	ErrorDocument string   `json:"errorDocument,omitempty"`
+	RedirectAllTo string   `json:"redirectAllTo,omitempty"`
+	RedirectRules []string `json:"redirectRules,omitempty"`
+}
+
+// WebsiteEndpoint returns the static website endpoint of a bucket in the given region.
+func WebsiteEndpoint(bucketName string, region string) string {
+	if legacyWebsiteEndpointRegions[region] {
+		return fmt.Sprintf("http://%s.s3-website-%s.amazonaws.com", bucketName, region)
+	}
+	return fmt.Sprintf("http://%s.s3-website.%s.amazonaws.com", bucketName, region)
+}
+
+func collectWebsite(s3Client *s3.Client, bucketState *BucketState, logBucket *log.Entry) {
+	bucketName := bucketState.Name
+	input := &s3.GetBucketWebsiteInput{Bucket: &bucketName, ExpectedBucketOwner: aws.ExpectedBucketOwner(bucketState.AccountID)}
+
+	output, err := s3Client.GetBucketWebsite(context.TODO(), input)
+	if err != nil {
+		bucketState.markReadError(err, "NoSuchWebsiteConfiguration", ControlWebsite)
+		// api error NoSuchWebsiteConfiguration: The specified bucket does not have a website configuration
+		var ae smithy.APIError
+		if errors.As(err, &ae) {
+			logBucket.Debugf("%s:  %s", ae.ErrorCode(), ae.ErrorMessage())
+		}
+	} else {
+		website := &bucketState.Website
+		website.Enabled = true
+		website.Endpoint = WebsiteEndpoint(bucketName, bucketState.Region)
+		if output.IndexDocument != nil && output.IndexDocument.Suffix != nil {
+			website.IndexDocument = *output.IndexDocument.Suffix
+		}
+		if output.ErrorDocument != nil && output.ErrorDocument.Key != nil {
+			website.ErrorDocument = *output.ErrorDocument.Key
+		}
+		if r := output.RedirectAllRequestsTo; r != nil && r.HostName != nil {
+			website.RedirectAllTo = RedirectTarget(string(r.Protocol), *r.HostName)
+		}
+		for _, rule := range output.RoutingRules {
+			if rule.Redirect == nil {
+				continue
+			}
+			var keyPrefix, httpErrorCode *string
+			if c := rule.Condition; c != nil {
+				keyPrefix, httpErrorCode = c.KeyPrefixEquals, c.HttpErrorCodeReturnedEquals
+			}
+			r := rule.Redirect
+			website.RedirectRules = append(website.RedirectRules,
+				RedirectRule(keyPrefix, httpErrorCode, string(r.Protocol), r.HostName, r.ReplaceKeyPrefixWith, r.ReplaceKeyWith))
+		}
+		logBucket.Debugf("Website: %+v", *website)
+	}
+}
+
+// RedirectTarget formats the target of a website redirect.
+func RedirectTarget(protocol string, hostName string) string {
+	if protocol == "" {
+		return hostName
+	}
+	return protocol + "://" + hostName
+}
+
+// RedirectRule formats a website routing rule as '<condition> -> <target>'.
+func RedirectRule(keyPrefix, httpErrorCode *string, protocol string, hostName, replaceKeyPrefixWith, replaceKeyWith *string) string {
+	condition := "*"
+	if keyPrefix != nil {
+		condition = *keyPrefix + "*"
+	}
+	if httpErrorCode != nil {
+		condition += " on HTTP " + *httpErrorCode
+	}
+	target := ""
+	if hostName != nil {
+		target = RedirectTarget(protocol, *hostName)
+	}
+	if replaceKeyPrefixWith != nil {
+		target += "/" + *replaceKeyPrefixWith + "*"
+	}
+	if replaceKeyWith != nil {
+		target += "/" + *replaceKeyWith
+	}
+	return condition + " -> " + target
+}
+
+func evaluateWebsite(website WebsiteReport, blocksPublicAccess bool) Finding {
+	if !website.Enabled {
+		return Finding{Control: ControlWebsite, Status: StatusPass, Message: "Static website hosting is not enabled"}
+	}
+
+	finding := Finding{Control: ControlWebsite, Status: StatusFail}
+	switch {
+	case website.RedirectAllTo != "":
+		finding.Message = fmt.Sprintf("Static website hosting is enabled at %s redirecting all requests to %s",
+			website.Endpoint, website.RedirectAllTo)
+	case len(website.RedirectRules) != 0:
+		finding.Message = fmt.Sprintf("Static website hosting is enabled at %s with %d redirect rule(s)",
+			website.Endpoint, len(website.RedirectRules))
+	default:
+		finding.Message = "Static website hosting is enabled at " + website.Endpoint
+	}
+
+	if !blocksPublicAccess {
+		finding.Severity = ControlSeverity(ControlWebsite).Raise()
+		finding.Message += "; bucket is not fully covered by 'Block public access'"
+	}
+	return finding
+}
@@ -5,10 +5,16 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
+	log "github.com/sirupsen/logrus"
 )
 
 // GetAccountID get the Account ID for the currently logged User.
+// S3 compatible endpoints have no STS; the account ID is empty then.
 func GetAccountID(config *aws.Config) (string, error) {
+	if UsesCustomEndpoint() {
+		log.Debug("Skipping account ID lookup for S3 compatible endpoint")
+		return "", nil
+	}
 	stsClient := sts.NewFromConfig(*config)
 	id, err := stsClient.GetCallerIdentity(context.TODO(), &sts.GetCallerIdentityInput{})
 	if err != nil {
@@ -16,3 +22,28 @@ func GetAccountID(config *aws.Config) (string, error) {
 	}
 	return *id.Account, err
 }
+
+// ExpectedBucketOwner returns the account ID to pass as expected bucket owner, or nil if unknown.
+func ExpectedBucketOwner(accountID string) *string {
+	if accountID == "" {
+		return nil
+	}
+	return &accountID
+}
+
+// GetCallerIdentity returns the ARN of the identity the audit runs as.
+// S3 compatible endpoints have no STS; the identity is empty then.
+func GetCallerIdentity() (string, error) {
+	if UsesCustomEndpoint() {
+		return "", nil
+	}
+	cfg, err := LoadConfig("")
+	if err != nil {
+		return "", err
+	}
+	id, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(id.Arn), nil
+}
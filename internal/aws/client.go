@@ -0,0 +1,71 @@
+package aws
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultEndpointRegion is used for S3 compatible endpoints if no region is configured.
+const defaultEndpointRegion = "us-east-1"
+
+// Endpoint configures an S3 compatible service, e.g. MinIO, Ceph RGW or LocalStack, instead of AWS S3.
+type Endpoint struct {
+	URL        string
+	PathStyle  bool // address buckets as <url>/<bucket> instead of <bucket>.<host>
+	SkipVerify bool // do not verify the endpoint's TLS certificate
+}
+
+var endpoint Endpoint
+
+// SetEndpoint configures all subsequently created clients to use the given endpoint.
+func SetEndpoint(e Endpoint) {
+	endpoint = e
+}
+
+// UsesCustomEndpoint reports whether an S3 compatible endpoint instead of AWS S3 is configured.
+func UsesCustomEndpoint() bool {
+	return endpoint.URL != ""
+}
+
+// LoadConfig loads the default AWS SDK configuration, optionally for a specific region.
+func LoadConfig(region string) (aws.Config, error) {
+	var opts []func(*config.LoadOptions) error
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		log.Errorf("Failed to load AWS SDK configuration: %v", err)
+		return cfg, err
+	}
+	if UsesCustomEndpoint() && cfg.Region == "" {
+		cfg.Region = defaultEndpointRegion
+	}
+	return cfg, nil
+}
+
+// NewS3Client returns an S3 client for the region that honors the configured endpoint.
+func NewS3Client(region string) (*s3.Client, error) {
+	cfg, err := LoadConfig(region)
+	if err != nil {
+		return nil, err
+	}
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if UsesCustomEndpoint() {
+			o.BaseEndpoint = &endpoint.URL
+			o.UsePathStyle = endpoint.PathStyle
+			// only the S3 compatible endpoint's certificate is not verified, never those of AWS services
+			if endpoint.SkipVerify {
+				o.HTTPClient = awshttp.NewBuildableClient().WithTransportOptions(func(tr *http.Transport) {
+					tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} //nolint:gosec // explicitly requested with --no-verify-ssl
+				})
+			}
+		}
+	}), nil
+}
@@ -2,79 +2,187 @@ package aws
 
 import (
 	"context"
-	"strings"
+	"errors"
+	"slices"
 
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
 	log "github.com/sirupsen/logrus"
 )
 
 type Bucket struct {
-	Name      string `json:"bucketName"`
-	AccountID string `json:"accountId"`
-	Region    string `json:"region"`
+	Name      string            `json:"bucketName"`
+	AccountID string            `json:"accountId"`
+	Region    string            `json:"region"`
+	Tags      map[string]string `json:"tags,omitempty"` // nil unless fetched to filter the buckets by tag
+}
+
+// RegionFilter restricts buckets to a set of regions.
+type RegionFilter struct {
+	Regions        []string // if set, only buckets in these regions
+	ExcludeRegions []string // buckets in these regions are never included
+}
+
+// Matches reports whether a bucket in the given region passes the filter.
+func (f RegionFilter) Matches(region string) bool {
+	if slices.Contains(f.ExcludeRegions, region) {
+		return false
+	}
+	return len(f.Regions) == 0 || slices.Contains(f.Regions, region)
 }
 
 var ctx = context.Background()
 
 func newS3Client() (*s3.Client, error) {
-	cfg, err := config.LoadDefaultConfig(ctx)
+	return NewS3Client("")
+}
+
+// GetBucketTags returns the tags of a bucket; a bucket without tags returns an empty map.
+func GetBucketTags(s3Client *s3.Client, bucket Bucket) (map[string]string, error) {
+	tags := map[string]string{}
+	output, err := s3Client.GetBucketTagging(ctx, &s3.GetBucketTaggingInput{Bucket: &bucket.Name, ExpectedBucketOwner: ExpectedBucketOwner(bucket.AccountID)})
 	if err != nil {
-		log.Errorf("Failed to load AWS SDK configuration: %v", err)
+		var ae smithy.APIError
+		if errors.As(err, &ae) && ae.ErrorCode() == "NoSuchTagSet" {
+			return tags, nil
+		}
 		return nil, err
 	}
-	return s3.NewFromConfig(cfg), nil
+
+	for _, tag := range output.TagSet {
+		if tag.Key != nil && tag.Value != nil {
+			tags[*tag.Key] = *tag.Value
+		}
+	}
+	return tags, nil
+}
+
+// listBucketsPageSize is the number of buckets requested per ListBuckets page (max. 10,000).
+const listBucketsPageSize int32 = 1000
+
+// paginateBuckets returns all buckets of a ListBuckets call, following continuation tokens
+// so accounts with a raised bucket quota are not truncated.
+func paginateBuckets(s3Client *s3.Client, input *s3.ListBucketsInput) ([]types.Bucket, error) {
+	if input.MaxBuckets == nil {
+		pageSize := listBucketsPageSize
+		input.MaxBuckets = &pageSize
+	}
+
+	var buckets []types.Bucket
+	paginator := s3.NewListBucketsPaginator(s3Client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			log.Errorf("Error listing S3 buckets: %v", err)
+			return nil, err
+		}
+		log.Debugf("Received page with %d buckets", len(page.Buckets))
+		buckets = append(buckets, page.Buckets...)
+	}
+	return buckets, nil
 }
 
 func GetBucketNamesWithPrefix(prefix string) ([]string, error) {
-	s3Client, _ := newS3Client()
-	result, err := s3Client.ListBuckets(ctx, &s3.ListBucketsInput{})
+	s3Client, err := newS3Client()
+	if err != nil {
+		return nil, err
+	}
+
+	input := &s3.ListBucketsInput{}
+	if prefix != "" {
+		input.Prefix = &prefix
+	}
+	buckets, err := paginateBuckets(s3Client, input)
 	if err != nil {
-		log.Errorf("Error listing S3 buckets: %v", err)
 		return nil, err
 	}
 
 	var bucketNames []string
-	for _, b := range result.Buckets {
-		name := b.Name
-		if strings.HasPrefix(*name, prefix) {
-			bucketNames = append(bucketNames, *name)
-		}
+	for _, b := range buckets {
+		bucketNames = append(bucketNames, *b.Name)
 	}
 	return bucketNames, nil
 }
 
 func GetBucketByName(name string) (Bucket, error) {
-	s3Client, _ := newS3Client()
-	cfg, _ := config.LoadDefaultConfig(ctx)
-	region, err := manager.GetBucketRegion(ctx, s3Client, name)
+	s3Client, err := newS3Client()
 	if err != nil {
 		return Bucket{}, err
 	}
+	region, err := manager.GetBucketRegion(ctx, s3Client, name)
+	if err != nil {
+		if !UsesCustomEndpoint() {
+			return Bucket{}, err
+		}
+		log.Debugf("Could not get region of bucket %s, using %s: %v", name, s3Client.Options().Region, err)
+		region = s3Client.Options().Region
+	}
+	cfg, _ := LoadConfig("")
 	accountID, _ := GetAccountID(&cfg)
-	bucket := Bucket{name, accountID, region}
+	bucket := Bucket{Name: name, AccountID: accountID, Region: region}
 
 	return bucket, nil
 }
 
-func GetBuckets() ([]Bucket, error) {
-	var buckets []Bucket
-
+// ListBuckets lists all buckets in the regions selected by the filter. If regions are given, they are
+// filtered server side; the bucket region is taken from the listing and only resolved per bucket
+// if the listing does not contain it.
+func ListBuckets(filter RegionFilter) ([]types.Bucket, error) {
 	log.Debug("Listing buckets")
-	s3Client, _ := newS3Client()
-	result, err := s3Client.ListBuckets(ctx, &s3.ListBucketsInput{})
+	s3Client, err := newS3Client()
+	if err != nil {
+		return nil, err
+	}
+
+	var inputs []*s3.ListBucketsInput
+	if len(filter.Regions) == 0 {
+		inputs = append(inputs, &s3.ListBucketsInput{})
+	}
+	for _, region := range filter.Regions {
+		inputs = append(inputs, &s3.ListBucketsInput{BucketRegion: &region})
+	}
+
+	var buckets []types.Bucket
+	for _, input := range inputs {
+		listed, err := paginateBuckets(s3Client, input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, b := range listed {
+			if b.BucketRegion == nil {
+				region, err := manager.GetBucketRegion(ctx, s3Client, *b.Name)
+				if err != nil {
+					log.Debugf("Could not get region of bucket %s, using %s: %v", *b.Name, s3Client.Options().Region, err)
+					region = s3Client.Options().Region
+				}
+				b.BucketRegion = &region
+			}
+			if !filter.Matches(*b.BucketRegion) {
+				log.Debugf("Bucket %s in region %s excluded by region filter", *b.Name, *b.BucketRegion)
+				continue
+			}
+			buckets = append(buckets, b)
+		}
+	}
+	return buckets, nil
+}
+
+// GetBuckets returns all buckets in the regions selected by the filter, see ListBuckets.
+func GetBuckets(filter RegionFilter) ([]Bucket, error) {
+	listed, err := ListBuckets(filter)
 	if err != nil {
-		log.Errorf("Error listing S3 buckets: %v", err)
 		return nil, err
 	}
 
-	for _, b := range result.Buckets {
-		name := b.Name
-		region, _ := manager.GetBucketRegion(ctx, s3Client, *name)
-		cfg, _ := config.LoadDefaultConfig(ctx)
-		accountID, _ := GetAccountID(&cfg)
-		buckets = append(buckets, Bucket{*name, accountID, region})
+	cfg, _ := LoadConfig("")
+	accountID, _ := GetAccountID(&cfg)
+
+	var buckets []Bucket
+	for _, b := range listed {
+		buckets = append(buckets, Bucket{Name: *b.Name, AccountID: accountID, Region: *b.BucketRegion})
 	}
 	return buckets, nil
 }
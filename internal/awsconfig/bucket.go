@@ -0,0 +1,456 @@
+package awsconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rollwagen/s3-cisbench/internal/audit"
+)
+
+// The supplementary configuration of an AWS::S3::Bucket configuration item, as serialized by AWS Config.
+
+type versioningConfiguration struct {
+	Status             string `json:"status"`
+	IsMfaDeleteEnabled *bool  `json:"isMfaDeleteEnabled"`
+}
+
+type encryptionConfiguration struct {
+	Rules []struct {
+		ApplyServerSideEncryptionByDefault *struct {
+			SSEAlgorithm   string  `json:"sseAlgorithm"`
+			KMSMasterKeyID *string `json:"kmsMasterKeyID"`
+		} `json:"applyServerSideEncryptionByDefault"`
+	} `json:"rules"`
+}
+
+type publicAccessBlockConfiguration struct {
+	BlockPublicAcls       bool `json:"blockPublicAcls"`
+	IgnorePublicAcls      bool `json:"ignorePublicAcls"`
+	BlockPublicPolicy     bool `json:"blockPublicPolicy"`
+	RestrictPublicBuckets bool `json:"restrictPublicBuckets"`
+}
+
+type bucketPolicy struct {
+	PolicyText *string `json:"policyText"`
+}
+
+type objectLockConfiguration struct {
+	ObjectLockEnabled string `json:"objectLockEnabled"`
+	Rule              *struct {
+		DefaultRetention *struct {
+			Mode  string `json:"mode"`
+			Days  *int32 `json:"days"`
+			Years *int32 `json:"years"`
+		} `json:"defaultRetention"`
+	} `json:"rule"`
+}
+
+type lifecycleConfiguration struct {
+	Rules []struct {
+		ID                                string `json:"id"`
+		Status                            string `json:"status"`
+		NoncurrentVersionExpirationInDays *int32 `json:"noncurrentVersionExpirationInDays"` // -1 if not set
+		AbortIncompleteMultipartUpload    *struct {
+			DaysAfterInitiation int32 `json:"daysAfterInitiation"`
+		} `json:"abortIncompleteMultipartUpload"`
+		Transitions []struct {
+			Days         *int32  `json:"days"`
+			Date         *string `json:"date"`
+			StorageClass string  `json:"storageClass"`
+		} `json:"transitions"`
+		NoncurrentVersionTransitions []struct {
+			Days         *int32 `json:"days"`
+			StorageClass string `json:"storageClass"`
+		} `json:"noncurrentVersionTransitions"`
+	} `json:"rules"`
+}
+
+type replicationRule struct {
+	ID                string `json:"id"`
+	Status            string `json:"status"`
+	DestinationConfig *struct {
+		BucketARN               string  `json:"bucketARN"`
+		Account                 *string `json:"account"`
+		EncryptionConfiguration *struct {
+			ReplicaKmsKeyID *string `json:"replicaKmsKeyID"`
+		} `json:"encryptionConfiguration"`
+	} `json:"destinationConfig"`
+	DeleteMarkerReplication *struct {
+		Status string `json:"status"`
+	} `json:"deleteMarkerReplication"`
+}
+
+type replicationConfiguration struct {
+	RoleARN string          `json:"roleARN"`
+	Rules   json.RawMessage `json:"rules"` // map of rule ID to rule, or list of rules
+}
+
+type notificationConfiguration struct {
+	Configurations map[string]struct {
+		Type        string   `json:"type"`
+		Events      []string `json:"events"`
+		TopicARN    *string  `json:"topicARN"`
+		QueueARN    *string  `json:"queueARN"`
+		FunctionARN *string  `json:"functionARN"`
+	} `json:"configurations"`
+}
+
+type websiteConfiguration struct {
+	IndexDocumentSuffix   *string `json:"indexDocumentSuffix"`
+	ErrorDocument         *string `json:"errorDocument"`
+	RedirectAllRequestsTo *struct {
+		HostName string `json:"hostName"`
+		Protocol string `json:"protocol"`
+	} `json:"redirectAllRequestsTo"`
+	RoutingRules []struct {
+		Condition *struct {
+			KeyPrefixEquals             *string `json:"keyPrefixEquals"`
+			HTTPErrorCodeReturnedEquals *string `json:"httpErrorCodeReturnedEquals"`
+		} `json:"condition"`
+		Redirect *struct {
+			HostName             *string `json:"hostName"`
+			Protocol             *string `json:"protocol"`
+			ReplaceKeyPrefixWith *string `json:"replaceKeyPrefixWith"`
+			ReplaceKeyWith       *string `json:"replaceKeyWith"`
+		} `json:"redirect"`
+	} `json:"routingRules"`
+}
+
+type accessControlList struct {
+	GrantList []struct {
+		Grantee    json.RawMessage `json:"grantee"` // group name, or object with the canonical user ID or email address
+		Permission string          `json:"permission"`
+	} `json:"grantList"`
+	Owner *struct {
+		ID string `json:"id"`
+	} `json:"owner"`
+}
+
+// aclGroupURIs are the URIs of the groups AWS Config records by name.
+var aclGroupURIs = map[string]string{
+	"AllUsers":           "http://acs.amazonaws.com/groups/global/AllUsers",
+	"AuthenticatedUsers": "http://acs.amazonaws.com/groups/global/AuthenticatedUsers",
+	"LogDelivery":        "http://acs.amazonaws.com/groups/s3/LogDelivery",
+}
+
+// aclPermissions map the permissions recorded by AWS Config to the ones of the S3 API.
+var aclPermissions = map[string]string{
+	"FullControl": "FULL_CONTROL",
+	"Read":        "READ",
+	"Write":       "WRITE",
+	"ReadAcp":     "READ_ACP",
+	"WriteAcp":    "WRITE_ACP",
+}
+
+// supplementary decodes the supplementary configuration with the given key into v;
+// it returns false if the key was not recorded or is null.
+func supplementary(item configurationItem, key string, v any) (bool, error) {
+	raw, ok := item.SupplementaryConfiguration[key]
+	if !ok || string(raw) == "null" || string(raw) == `""` {
+		return false, nil
+	}
+	if err := decode(raw, v); err != nil {
+		return false, fmt.Errorf("could not parse %s: %v", key, err)
+	}
+	return true, nil
+}
+
+func newBucketState(item configurationItem) (audit.BucketState, error) {
+	state := audit.BucketState{
+		Name:      item.ResourceName,
+		AccountID: item.AWSAccountID,
+		Region:    item.Region,
+		Tags:      item.Tags,
+	}
+
+	var versioning versioningConfiguration
+	if _, err := supplementary(item, "BucketVersioningConfiguration", &versioning); err != nil {
+		return state, err
+	}
+	state.VersioningEnabled = versioning.Status == "Enabled"
+	state.MFADelete = versioning.IsMfaDeleteEnabled != nil && *versioning.IsMfaDeleteEnabled
+
+	var encryption encryptionConfiguration
+	if _, err := supplementary(item, "ServerSideEncryptionConfiguration", &encryption); err != nil {
+		return state, err
+	}
+	for _, rule := range encryption.Rules {
+		if rule.ApplyServerSideEncryptionByDefault == nil {
+			continue
+		}
+		state.ServerSideEncryptionEnabled = true
+		state.EncryptionAlgorithm = rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm
+		if state.EncryptionAlgorithm == "aws:kms" && rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID != nil {
+			state.CustomerManagedKey = true
+		}
+	}
+
+	var bpa publicAccessBlockConfiguration
+	if _, err := supplementary(item, "PublicAccessBlockConfiguration", &bpa); err != nil {
+		return state, err
+	}
+	state.BlockPublicAccess.BlockPublicAcls = bpa.BlockPublicAcls
+	state.BlockPublicAccess.BlockPublicPolicy = bpa.BlockPublicPolicy
+	state.BlockPublicAccess.IgnorePublicAcls = bpa.IgnorePublicAcls
+	state.BlockPublicAccess.RestrictPublicBuckets = bpa.RestrictPublicBuckets
+
+	var policy bucketPolicy
+	if _, err := supplementary(item, "BucketPolicy", &policy); err != nil {
+		return state, err
+	}
+	if policy.PolicyText != nil {
+		state.Policy = *policy.PolicyText
+	}
+
+	var objectLock objectLockConfiguration
+	recorded, err := supplementary(item, "ObjectLockConfiguration", &objectLock)
+	if err != nil {
+		return state, err
+	}
+	if !recorded {
+		state.MarkUnavailable(notRecordedReason, audit.ControlObjectLock)
+	}
+	state.ObjectLock.Enabled = objectLock.ObjectLockEnabled == "Enabled"
+	if objectLock.Rule != nil && objectLock.Rule.DefaultRetention != nil {
+		retention := objectLock.Rule.DefaultRetention
+		state.ObjectLock.Mode = retention.Mode
+		if retention.Days != nil {
+			state.ObjectLock.RetentionDays = *retention.Days
+		}
+		if retention.Years != nil {
+			state.ObjectLock.RetentionDays = *retention.Years * 365
+		}
+	}
+
+	if err := setLifecycle(&state, item); err != nil {
+		return state, err
+	}
+	if err := setReplication(&state, item); err != nil {
+		return state, err
+	}
+	if err := setNotification(&state, item); err != nil {
+		return state, err
+	}
+	if err := setWebsite(&state, item); err != nil {
+		return state, err
+	}
+	if err := setACL(&state, item); err != nil {
+		return state, err
+	}
+
+	// CORS rules are not part of the recorded configuration
+	state.MarkUnavailable(notRecordedReason, audit.ControlCORS)
+
+	return state, nil
+}
+
+func setLifecycle(state *audit.BucketState, item configurationItem) error {
+	var lifecycle lifecycleConfiguration
+	if _, err := supplementary(item, "BucketLifecycleConfiguration", &lifecycle); err != nil {
+		return err
+	}
+
+	for _, rule := range lifecycle.Rules {
+		ruleReport := audit.LifecycleRuleReport{ID: rule.ID, Enabled: rule.Status == "Enabled"}
+		noncurrentExpiration := rule.NoncurrentVersionExpirationInDays != nil && *rule.NoncurrentVersionExpirationInDays >= 0
+		if noncurrentExpiration {
+			ruleReport.NoncurrentVersionExpirationDays = *rule.NoncurrentVersionExpirationInDays
+		}
+		if rule.AbortIncompleteMultipartUpload != nil {
+			ruleReport.AbortIncompleteMultipartUploadDays = rule.AbortIncompleteMultipartUpload.DaysAfterInitiation
+		}
+		for _, t := range rule.Transitions {
+			switch {
+			case t.Days != nil && *t.Days >= 0:
+				ruleReport.Transitions = append(ruleReport.Transitions, fmt.Sprintf("%s after %d days", t.StorageClass, *t.Days))
+			case t.Date != nil:
+				ruleReport.Transitions = append(ruleReport.Transitions, fmt.Sprintf("%s on %s", t.StorageClass, *t.Date))
+			}
+		}
+		for _, t := range rule.NoncurrentVersionTransitions {
+			if t.Days != nil && *t.Days >= 0 {
+				ruleReport.NoncurrentVersionTransitions = append(ruleReport.NoncurrentVersionTransitions,
+					fmt.Sprintf("%s after %d days", t.StorageClass, *t.Days))
+			}
+		}
+
+		state.Lifecycle.Rules = append(state.Lifecycle.Rules, ruleReport)
+		if ruleReport.Enabled && noncurrentExpiration {
+			state.Lifecycle.NoncurrentVersionExpiration = true
+		}
+		if ruleReport.Enabled && rule.AbortIncompleteMultipartUpload != nil {
+			state.Lifecycle.AbortIncompleteMultipartUpload = true
+		}
+	}
+	return nil
+}
+
+func setReplication(state *audit.BucketState, item configurationItem) error {
+	var replication replicationConfiguration
+	recorded, err := supplementary(item, "BucketReplicationConfiguration", &replication)
+	if err != nil || !recorded {
+		return err
+	}
+	state.Replication.Role = replication.RoleARN
+
+	var rules []replicationRule
+	if strings.HasPrefix(strings.TrimSpace(string(replication.Rules)), "{") {
+		var byID map[string]replicationRule
+		if err := json.Unmarshal(replication.Rules, &byID); err != nil {
+			return fmt.Errorf("could not parse replication rules: %v", err)
+		}
+		ids := make([]string, 0, len(byID))
+		for id := range byID {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			rule := byID[id]
+			if rule.ID == "" {
+				rule.ID = id
+			}
+			rules = append(rules, rule)
+		}
+	} else if len(replication.Rules) != 0 {
+		if err := json.Unmarshal(replication.Rules, &rules); err != nil {
+			return fmt.Errorf("could not parse replication rules: %v", err)
+		}
+	}
+
+	for _, rule := range rules {
+		// the destination region, and without owner override its account, is not recorded; see resolveDestinations
+		ruleReport := audit.ReplicationRuleReport{
+			ID:      rule.ID,
+			Enabled: rule.Status == "Enabled",
+		}
+		if d := rule.DestinationConfig; d != nil {
+			ruleReport.DestinationBucket = strings.TrimPrefix(d.BucketARN, "arn:aws:s3:::")
+			if d.Account != nil {
+				ruleReport.DestinationAccount = *d.Account
+			}
+			if d.EncryptionConfiguration != nil && d.EncryptionConfiguration.ReplicaKmsKeyID != nil {
+				ruleReport.ReplicaKMSKeyID = *d.EncryptionConfiguration.ReplicaKmsKeyID
+			}
+		}
+		if rule.DeleteMarkerReplication != nil {
+			ruleReport.DeleteMarkerReplication = rule.DeleteMarkerReplication.Status == "Enabled"
+		}
+		state.Replication.Rules = append(state.Replication.Rules, ruleReport)
+	}
+	return nil
+}
+
+func setNotification(state *audit.BucketState, item configurationItem) error {
+	var notification notificationConfiguration
+	if _, err := supplementary(item, "BucketNotificationConfiguration", &notification); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(notification.Configurations))
+	for name := range notification.Configurations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		c := notification.Configurations[name]
+		var destinationType string
+		var arn *string
+		switch {
+		case c.TopicARN != nil:
+			destinationType, arn = "sns", c.TopicARN
+		case c.QueueARN != nil:
+			destinationType, arn = "sqs", c.QueueARN
+		case c.FunctionARN != nil:
+			destinationType, arn = "lambda", c.FunctionARN
+		default:
+			continue
+		}
+		state.Notification.Destinations = append(state.Notification.Destinations,
+			audit.NewNotificationDestination(destinationType, *arn, c.Events, state.AccountID))
+	}
+	return nil
+}
+
+func setWebsite(state *audit.BucketState, item configurationItem) error {
+	var website websiteConfiguration
+	recorded, err := supplementary(item, "BucketWebsiteConfiguration", &website)
+	if err != nil || !recorded {
+		return err
+	}
+
+	w := &state.Website
+	w.Enabled = website.IndexDocumentSuffix != nil || website.RedirectAllRequestsTo != nil
+	if !w.Enabled {
+		return nil
+	}
+	w.Endpoint = audit.WebsiteEndpoint(state.Name, state.Region)
+	if website.IndexDocumentSuffix != nil {
+		w.IndexDocument = *website.IndexDocumentSuffix
+	}
+	if website.ErrorDocument != nil {
+		w.ErrorDocument = *website.ErrorDocument
+	}
+	if r := website.RedirectAllRequestsTo; r != nil {
+		w.RedirectAllTo = audit.RedirectTarget(r.Protocol, r.HostName)
+	}
+	for _, rule := range website.RoutingRules {
+		if rule.Redirect == nil {
+			continue
+		}
+		var keyPrefix, httpErrorCode *string
+		if c := rule.Condition; c != nil {
+			keyPrefix, httpErrorCode = c.KeyPrefixEquals, c.HTTPErrorCodeReturnedEquals
+		}
+		r := rule.Redirect
+		var protocol string
+		if r.Protocol != nil {
+			protocol = *r.Protocol
+		}
+		w.RedirectRules = append(w.RedirectRules,
+			audit.RedirectRule(keyPrefix, httpErrorCode, protocol, r.HostName, r.ReplaceKeyPrefixWith, r.ReplaceKeyWith))
+	}
+	return nil
+}
+
+func setACL(state *audit.BucketState, item configurationItem) error {
+	var acl accessControlList
+	if _, err := supplementary(item, "AccessControlList", &acl); err != nil {
+		return err
+	}
+
+	if acl.Owner != nil {
+		state.ACL.Owner = acl.Owner.ID
+	}
+	for _, g := range acl.GrantList {
+		permission, ok := aclPermissions[g.Permission]
+		if !ok {
+			permission = g.Permission
+		}
+		grant := audit.ACLGrant{Permission: permission}
+
+		var group string
+		var grantee struct {
+			ID           *string `json:"id"`
+			EmailAddress *string `json:"emailAddress"`
+		}
+		switch {
+		case json.Unmarshal(g.Grantee, &group) == nil:
+			grant.Type = "Group"
+			grant.Grantee = aclGroupURIs[group]
+			if grant.Grantee == "" {
+				grant.Grantee = group
+			}
+		case json.Unmarshal(g.Grantee, &grantee) == nil && grantee.ID != nil:
+			grant.Type, grant.Grantee = "CanonicalUser", *grantee.ID
+		case grantee.EmailAddress != nil:
+			grant.Type, grant.Grantee = "AmazonCustomerByEmail", *grantee.EmailAddress
+		default:
+			continue
+		}
+		state.ACL.Grants = append(state.ACL.Grants, grant)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+// Package awsconfig reads S3 bucket configurations recorded by AWS Config, so buckets
+// can be audited offline without any call to AWS.
+package awsconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rollwagen/s3-cisbench/internal/audit"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	resourceTypeBucket = "AWS::S3::Bucket"
+	statusDeleted      = "ResourceDeleted"
+	statusNotRecorded  = "ResourceNotRecorded"
+	notRecordedReason  = "Not recorded in AWS Config configuration item"
+)
+
+// configurationItem is the subset of an AWS Config configuration item needed to audit S3 buckets.
+// Snapshot and history files use 'awsAccountId', the configservice API uses 'accountId'.
+type configurationItem struct {
+	ResourceType               string                     `json:"resourceType"`
+	ResourceName               string                     `json:"resourceName"`
+	ResourceID                 string                     `json:"resourceId"`
+	AWSAccountID               string                     `json:"awsAccountId"`
+	AccountID                  string                     `json:"accountId"`
+	Region                     string                     `json:"awsRegion"`
+	Status                     string                     `json:"configurationItemStatus"`
+	CaptureTime                string                     `json:"configurationItemCaptureTime"`
+	Tags                       map[string]string          `json:"tags"`
+	SupplementaryConfiguration map[string]json.RawMessage `json:"supplementaryConfiguration"`
+}
+
+// file covers snapshot/history files, 'get-resource-config-history' and 'batch-get-resource-config' output.
+type file struct {
+	ConfigurationItems     []configurationItem `json:"configurationItems"`
+	BaseConfigurationItems []configurationItem `json:"baseConfigurationItems"`
+}
+
+// decode unmarshals a value that is either a JSON object or, as in many Config files, a JSON encoded string.
+func decode(raw json.RawMessage, v any) error {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		raw = []byte(s)
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// readItems reads all configuration items of a file, which may also hold a single item or a list of items.
+func readItems(path string) ([]configurationItem, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := strings.TrimSpace(string(b))
+	if strings.HasPrefix(trimmed, "[") {
+		var items []configurationItem
+		if err := json.Unmarshal(b, &items); err != nil {
+			return nil, fmt.Errorf("could not parse %s: %v", path, err)
+		}
+		return items, nil
+	}
+
+	var f file
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", path, err)
+	}
+	items := append(f.ConfigurationItems, f.BaseConfigurationItems...)
+	if len(items) == 0 {
+		var item configurationItem
+		if err := json.Unmarshal(b, &item); err == nil && item.ResourceType != "" {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// LoadBuckets reads AWS Config snapshot, history or configuration item files and returns one bucket state per
+// recorded S3 bucket with the collected configuration; the controls still need to be evaluated.
+// If a bucket is recorded multiple times, the most recent configuration item is used.
+func LoadBuckets(paths []string) ([]audit.BucketState, error) {
+	latest := map[string]configurationItem{}
+	var keys []string
+
+	for _, path := range paths {
+		items, err := readItems(path)
+		if err != nil {
+			return nil, err
+		}
+		log.Debugf("Read %d configuration items from %s", len(items), path)
+
+		for _, item := range items {
+			if item.ResourceType != resourceTypeBucket {
+				continue
+			}
+			if item.AWSAccountID == "" {
+				item.AWSAccountID = item.AccountID
+			}
+			if item.ResourceName == "" {
+				item.ResourceName = item.ResourceID
+			}
+			key := item.AWSAccountID + "/" + item.Region + "/" + item.ResourceName
+			previous, seen := latest[key]
+			if !seen {
+				keys = append(keys, key)
+			}
+			// capture times are ISO 8601 timestamps, comparable as strings
+			if !seen || item.CaptureTime >= previous.CaptureTime {
+				latest[key] = item
+			}
+		}
+	}
+
+	var states []audit.BucketState
+	for _, key := range keys {
+		item := latest[key]
+		if item.Status == statusDeleted || item.Status == statusNotRecorded {
+			log.Debugf("Skipping bucket %s with status %s", item.ResourceName, item.Status)
+			continue
+		}
+		state, err := newBucketState(item)
+		if err != nil {
+			return nil, fmt.Errorf("bucket %s: %v", item.ResourceName, err)
+		}
+		states = append(states, state)
+	}
+	resolveDestinations(states)
+	return states, nil
+}
+
+// resolveDestinations sets the region, and unless recorded the account, of replication destinations
+// recorded in the same files, e.g. of an aggregator; those of other destinations remain unknown.
+func resolveDestinations(states []audit.BucketState) {
+	destinations := map[string]audit.BucketState{}
+	for _, state := range states {
+		destinations[state.Name] = state
+	}
+	for _, state := range states {
+		for i, rule := range state.Replication.Rules {
+			destination := destinations[rule.DestinationBucket]
+			state.Replication.Rules[i].DestinationRegion = destination.Region
+			if rule.DestinationAccount == "" {
+				state.Replication.Rules[i].DestinationAccount = destination.AccountID
+			}
+		}
+	}
+}
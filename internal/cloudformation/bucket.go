@@ -0,0 +1,383 @@
+package cloudformation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rollwagen/s3-cisbench/internal/audit"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	typeBucket       = "AWS::S3::Bucket"
+	typeBucketPolicy = "AWS::S3::BucketPolicy"
+
+	// S3 applies SSE-S3 to all buckets without a default encryption configuration
+	defaultEncryptionAlgorithm = "AES256"
+	daysPerYear                = 365
+
+	bpaReason    = "Public access block not configured in template (new buckets block all public access by default)"
+	policyReason = "Bucket policy cannot be resolved from template"
+)
+
+// Property values are resolved to Go values; CloudFormation accepts strings for numbers and booleans.
+
+func str(m map[string]any, key string) string {
+	return toString(m[key])
+}
+
+func optional(m map[string]any, key string) *string {
+	if s := str(m, key); s != "" {
+		return &s
+	}
+	return nil
+}
+
+func boolean(m map[string]any, key string) bool {
+	switch v := m[key].(type) {
+	case bool:
+		return v
+	case string:
+		b, _ := strconv.ParseBool(v)
+		return b
+	}
+	return false
+}
+
+func number(m map[string]any, key string) (int32, bool) {
+	switch v := m[key].(type) {
+	case int:
+		return int32(v), true
+	case float64:
+		return int32(v), true
+	case string:
+		n, err := strconv.Atoi(v)
+		return int32(n), err == nil
+	}
+	return 0, false
+}
+
+func object(m map[string]any, key string) map[string]any {
+	o, _ := m[key].(map[string]any)
+	return o
+}
+
+func objects(m map[string]any, key string) []map[string]any {
+	list, _ := m[key].([]any)
+	var values []map[string]any
+	for _, item := range list {
+		if o, ok := item.(map[string]any); ok {
+			values = append(values, o)
+		}
+	}
+	return values
+}
+
+func strs(m map[string]any, key string) []string {
+	list, _ := m[key].([]any)
+	var values []string
+	for _, item := range list {
+		values = append(values, toString(item))
+	}
+	return values
+}
+
+// bucketName returns the resolved BucketName of a bucket resource, or its logical ID if not set.
+func (t *template) bucketName(logicalID string) string {
+	key := "BucketName:" + logicalID
+	if t.resolving[key] {
+		return logicalID
+	}
+	t.resolving[key] = true
+	defer delete(t.resolving, key)
+
+	properties := mappingValue(t.resources[logicalID], "Properties")
+	if name := toString(t.value(mappingValue(properties, "BucketName"))); name != "" {
+		return name
+	}
+	return logicalID
+}
+
+// created reports whether a resource is created, i.e. has no condition evaluating to false.
+func (t *template) created(logicalID string) bool {
+	condition := mappingValue(t.resources[logicalID], "Condition")
+	return condition == nil || t.condition(condition.Value)
+}
+
+// LoadTemplates reads CloudFormation templates in YAML or JSON format and returns one bucket state per
+// AWS::S3::Bucket with its configuration; the controls still need to be evaluated. The state's
+// Resource and the findings' resources refer to the logical IDs and template lines.
+func LoadTemplates(paths []string, options Options) ([]audit.BucketState, error) {
+	var states []audit.BucketState
+	for _, path := range paths {
+		t, err := parseTemplate(path, options)
+		if err != nil {
+			return nil, err
+		}
+
+		byLogicalID := map[string]*audit.BucketState{}
+		var logicalIDs []string
+		for _, logicalID := range t.order {
+			if t.resourceType(logicalID) != typeBucket {
+				continue
+			}
+			if !t.created(logicalID) {
+				log.Debugf("Skipping %s, its condition is false", logicalID)
+				continue
+			}
+			state := t.newBucketState(logicalID)
+			byLogicalID[logicalID] = &state
+			logicalIDs = append(logicalIDs, logicalID)
+		}
+
+		for _, logicalID := range t.order {
+			if t.resourceType(logicalID) == typeBucketPolicy && t.created(logicalID) {
+				t.applyBucketPolicy(logicalID, byLogicalID)
+			}
+		}
+
+		byName := map[string]*audit.BucketState{}
+		for _, logicalID := range logicalIDs {
+			if _, ok := byName[byLogicalID[logicalID].Name]; !ok {
+				byName[byLogicalID[logicalID].Name] = byLogicalID[logicalID]
+			}
+		}
+		for _, logicalID := range logicalIDs {
+			state := byLogicalID[logicalID]
+			for i, rule := range state.Replication.Rules {
+				if destination, ok := byName[rule.DestinationBucket]; ok {
+					state.Replication.Rules[i].DestinationRegion = destination.Region
+				}
+			}
+			states = append(states, *state)
+		}
+	}
+	return states, nil
+}
+
+func (t *template) newBucketState(logicalID string) audit.BucketState {
+	resource := t.resources[logicalID]
+	propertiesNode := mappingValue(resource, "Properties")
+	properties, _ := t.value(propertiesNode).(map[string]any)
+	at := func(property string) string {
+		line := keyLine(propertiesNode, property)
+		if line == 0 {
+			line = t.keyLines[logicalID]
+		}
+		return t.location(logicalID+"."+property, line)
+	}
+
+	state := audit.BucketState{
+		Name:      t.bucketName(logicalID),
+		Resource:  t.location(logicalID, t.keyLines[logicalID]),
+		AccountID: t.options.AccountID,
+		Region:    t.options.Region, // empty instead of the ${AWS::Region} placeholder if not given
+	}
+
+	for _, tag := range objects(properties, "Tags") {
+		if state.Tags == nil {
+			state.Tags = map[string]string{}
+		}
+		state.Tags[str(tag, "Key")] = str(tag, "Value")
+	}
+
+	// MFA delete cannot be configured by CloudFormation
+	if versioning := object(properties, "VersioningConfiguration"); versioning != nil {
+		state.VersioningEnabled = str(versioning, "Status") == "Enabled"
+		state.SetResource(at("VersioningConfiguration"), audit.ControlVersioning)
+	}
+
+	state.ServerSideEncryptionEnabled = true
+	state.EncryptionAlgorithm = defaultEncryptionAlgorithm
+	if encryption := object(properties, "BucketEncryption"); encryption != nil {
+		for _, rule := range objects(encryption, "ServerSideEncryptionConfiguration") {
+			if byDefault := object(rule, "ServerSideEncryptionByDefault"); byDefault != nil {
+				state.EncryptionAlgorithm = str(byDefault, "SSEAlgorithm")
+				state.CustomerManagedKey = state.EncryptionAlgorithm == "aws:kms" && str(byDefault, "KMSMasterKeyID") != ""
+			}
+		}
+		state.SetResource(at("BucketEncryption"), audit.ControlEncryption)
+	}
+
+	if bpa := object(properties, "PublicAccessBlockConfiguration"); bpa != nil {
+		state.BlockPublicAccess.BlockPublicAcls = boolean(bpa, "BlockPublicAcls")
+		state.BlockPublicAccess.BlockPublicPolicy = boolean(bpa, "BlockPublicPolicy")
+		state.BlockPublicAccess.IgnorePublicAcls = boolean(bpa, "IgnorePublicAcls")
+		state.BlockPublicAccess.RestrictPublicBuckets = boolean(bpa, "RestrictPublicBuckets")
+		state.SetResource(at("PublicAccessBlockConfiguration"), audit.ControlBlockPublicAccess)
+	} else {
+		state.MarkUnavailable(bpaReason, audit.ControlBlockPublicAccess)
+	}
+
+	state.ObjectLock.Enabled = boolean(properties, "ObjectLockEnabled")
+	if lock := object(properties, "ObjectLockConfiguration"); lock != nil {
+		state.ObjectLock.Enabled = state.ObjectLock.Enabled || str(lock, "ObjectLockEnabled") == "Enabled"
+		if retention := object(object(lock, "Rule"), "DefaultRetention"); retention != nil {
+			state.ObjectLock.Mode = str(retention, "Mode")
+			if days, ok := number(retention, "Days"); ok {
+				state.ObjectLock.RetentionDays = days
+			}
+			if years, ok := number(retention, "Years"); ok {
+				state.ObjectLock.RetentionDays = years * daysPerYear
+			}
+		}
+		state.SetResource(at("ObjectLockConfiguration"), audit.ControlObjectLock)
+	}
+
+	if lifecycle := object(properties, "LifecycleConfiguration"); lifecycle != nil {
+		setLifecycle(&state, lifecycle)
+		state.SetResource(at("LifecycleConfiguration"), audit.ControlLifecycle)
+	}
+	if replication := object(properties, "ReplicationConfiguration"); replication != nil {
+		setReplication(&state, replication)
+		state.SetResource(at("ReplicationConfiguration"), audit.ControlReplication)
+	}
+	if website := object(properties, "WebsiteConfiguration"); website != nil {
+		setWebsite(&state, website)
+		state.SetResource(at("WebsiteConfiguration"), audit.ControlWebsite)
+	}
+	if cors := object(properties, "CorsConfiguration"); cors != nil {
+		for _, rule := range objects(cors, "CorsRules") {
+			state.CORS.Rules = append(state.CORS.Rules,
+				audit.NewCORSRuleReport(optional(rule, "Id"), strs(rule, "AllowedOrigins"), strs(rule, "AllowedMethods")))
+		}
+		state.SetResource(at("CorsConfiguration"), audit.ControlCORS)
+	}
+	if notification := object(properties, "NotificationConfiguration"); notification != nil {
+		setNotification(&state, notification)
+		state.SetResource(at("NotificationConfiguration"), audit.ControlNotification)
+	}
+
+	return state
+}
+
+func setLifecycle(state *audit.BucketState, lifecycle map[string]any) {
+	for _, rule := range objects(lifecycle, "Rules") {
+		ruleReport := audit.LifecycleRuleReport{ID: str(rule, "Id"), Enabled: str(rule, "Status") == "Enabled"}
+
+		abort := object(rule, "AbortIncompleteMultipartUpload")
+		if abort != nil {
+			ruleReport.AbortIncompleteMultipartUploadDays, _ = number(abort, "DaysAfterInitiation")
+		}
+		noncurrentDays, noncurrent := number(rule, "NoncurrentVersionExpirationInDays")
+		if expiration := object(rule, "NoncurrentVersionExpiration"); expiration != nil {
+			noncurrentDays, noncurrent = number(expiration, "NoncurrentDays")
+		}
+		ruleReport.NoncurrentVersionExpirationDays = noncurrentDays
+
+		for _, transition := range objects(rule, "Transitions") {
+			if days, ok := number(transition, "TransitionInDays"); ok {
+				ruleReport.Transitions = append(ruleReport.Transitions, fmt.Sprintf("%s after %d days", str(transition, "StorageClass"), days))
+			} else if date := str(transition, "TransitionDate"); date != "" {
+				ruleReport.Transitions = append(ruleReport.Transitions, fmt.Sprintf("%s on %s", str(transition, "StorageClass"), date))
+			}
+		}
+		for _, transition := range objects(rule, "NoncurrentVersionTransitions") {
+			if days, ok := number(transition, "TransitionInDays"); ok {
+				ruleReport.NoncurrentVersionTransitions = append(ruleReport.NoncurrentVersionTransitions,
+					fmt.Sprintf("%s after %d days", str(transition, "StorageClass"), days))
+			}
+		}
+
+		state.Lifecycle.Rules = append(state.Lifecycle.Rules, ruleReport)
+		if ruleReport.Enabled && noncurrent {
+			state.Lifecycle.NoncurrentVersionExpiration = true
+		}
+		if ruleReport.Enabled && abort != nil {
+			state.Lifecycle.AbortIncompleteMultipartUpload = true
+		}
+	}
+}
+
+func setReplication(state *audit.BucketState, replication map[string]any) {
+	state.Replication.Role = str(replication, "Role")
+	for _, rule := range objects(replication, "Rules") {
+		ruleReport := audit.ReplicationRuleReport{
+			ID:                      str(rule, "Id"),
+			Enabled:                 str(rule, "Status") == "Enabled",
+			DeleteMarkerReplication: str(object(rule, "DeleteMarkerReplication"), "Status") == "Enabled",
+		}
+		if d := object(rule, "Destination"); d != nil {
+			ruleReport.DestinationBucket = strings.TrimPrefix(str(d, "Bucket"), "arn:aws:s3:::")
+			ruleReport.DestinationAccount = str(d, "Account")
+			ruleReport.ReplicaKMSKeyID = str(object(d, "EncryptionConfiguration"), "ReplicaKmsKeyID")
+		}
+		state.Replication.Rules = append(state.Replication.Rules, ruleReport)
+	}
+}
+
+func setWebsite(state *audit.BucketState, website map[string]any) {
+	w := &state.Website
+	w.Enabled = true
+	w.Endpoint = audit.WebsiteEndpoint(state.Name, state.Region)
+	w.IndexDocument = str(website, "IndexDocument")
+	w.ErrorDocument = str(website, "ErrorDocument")
+	if redirect := object(website, "RedirectAllRequestsTo"); redirect != nil {
+		w.RedirectAllTo = audit.RedirectTarget(str(redirect, "Protocol"), str(redirect, "HostName"))
+	}
+	for _, rule := range objects(website, "RoutingRules") {
+		redirect := object(rule, "RedirectRule")
+		if redirect == nil {
+			continue
+		}
+		condition := object(rule, "RoutingRuleCondition")
+		w.RedirectRules = append(w.RedirectRules, audit.RedirectRule(
+			optional(condition, "KeyPrefixEquals"), optional(condition, "HttpErrorCodeReturnedEquals"),
+			str(redirect, "Protocol"), optional(redirect, "HostName"),
+			optional(redirect, "ReplaceKeyPrefixWith"), optional(redirect, "ReplaceKeyWith")))
+	}
+}
+
+func setNotification(state *audit.BucketState, notification map[string]any) {
+	n := &state.Notification
+	n.EventBridgeEnabled = boolean(object(notification, "EventBridgeConfiguration"), "EventBridgeEnabled")
+	for _, d := range []struct{ destinationType, list, arn string }{
+		{"sns", "TopicConfigurations", "Topic"},
+		{"sqs", "QueueConfigurations", "Queue"},
+		{"lambda", "LambdaConfigurations", "Function"},
+	} {
+		for _, c := range objects(notification, d.list) {
+			n.Destinations = append(n.Destinations,
+				audit.NewNotificationDestination(d.destinationType, str(c, d.arn), []string{str(c, "Event")}, state.AccountID))
+		}
+	}
+}
+
+// applyBucketPolicy evaluates an AWS::S3::BucketPolicy for the bucket it refers to.
+func (t *template) applyBucketPolicy(logicalID string, byLogicalID map[string]*audit.BucketState) {
+	propertiesNode := mappingValue(t.resources[logicalID], "Properties")
+	bucketNode := mappingValue(propertiesNode, "Bucket")
+
+	if bucketNode == nil {
+		log.Warnf("Skipping %s, it has no bucket", logicalID)
+		return
+	}
+	var state *audit.BucketState
+	if name, arg, ok := intrinsic(bucketNode); ok && name == "Ref" {
+		state = byLogicalID[toString(t.value(arg))]
+	}
+	if state == nil {
+		name := toString(t.value(bucketNode))
+		for _, id := range t.order { // the first bucket of the name in the template
+			if r, ok := byLogicalID[id]; ok && r.Name == name {
+				state = r
+				break
+			}
+		}
+	}
+	if state == nil {
+		log.Warnf("Skipping %s, its bucket is not defined in %s", logicalID, t.file)
+		return
+	}
+
+	state.SetResource(t.location(logicalID, t.keyLines[logicalID]), audit.PolicyControls()...)
+	document := t.value(mappingValue(propertiesNode, "PolicyDocument"))
+	policy, err := json.Marshal(document)
+	if _, ok := document.(map[string]any); !ok || err != nil {
+		state.MarkUnavailable(policyReason, audit.PolicyControls()...)
+		return
+	}
+	state.Policy = string(policy)
+}
@@ -0,0 +1,406 @@
+// Package cloudformation extracts the S3 bucket configuration of CloudFormation templates, so buckets
+// can be audited before they are deployed.
+package cloudformation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// noValue is the result of 'Ref: AWS::NoValue'; properties and list items with this value are removed.
+type noValue struct{}
+
+// placeholder represents a value that cannot be resolved without deploying the template.
+func placeholder(name string) string {
+	return "${" + name + "}"
+}
+
+// Options hold the values of pseudo parameters and parameters used to resolve intrinsic functions.
+type Options struct {
+	AccountID  string
+	Region     string
+	Parameters map[string]string // overrides of the parameters' default values
+}
+
+type template struct {
+	file       string
+	options    Options
+	resources  map[string]*yaml.Node // logical ID -> resource definition
+	order      []string              // logical IDs in template order
+	keyLines   map[string]int        // logical ID -> line of the resource
+	parameters map[string]*yaml.Node
+	mappings   *yaml.Node
+	conditions map[string]*yaml.Node
+	resolving  map[string]bool // guards against reference cycles
+}
+
+// mappingValue returns the value of key in a mapping node.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// keyLine returns the line of key in a mapping node, or 0.
+func keyLine(node *yaml.Node, key string) int {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return 0
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i].Line
+		}
+	}
+	return 0
+}
+
+func mappingNodes(node *yaml.Node) (map[string]*yaml.Node, []string, map[string]int) {
+	nodes := map[string]*yaml.Node{}
+	var keys []string
+	lines := map[string]int{}
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nodes, keys, lines
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		nodes[key] = node.Content[i+1]
+		keys = append(keys, key)
+		lines[key] = node.Content[i].Line
+	}
+	return nodes, keys, lines
+}
+
+func parseTemplate(path string, options Options) (*template, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// JSON templates are valid YAML
+	var document yaml.Node
+	if err := yaml.Unmarshal(b, &document); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", path, err)
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s is not a CloudFormation template", path)
+	}
+	root := document.Content[0]
+
+	t := &template{file: path, options: options, mappings: mappingValue(root, "Mappings"), resolving: map[string]bool{}}
+	t.resources, t.order, t.keyLines = mappingNodes(mappingValue(root, "Resources"))
+	t.parameters, _, _ = mappingNodes(mappingValue(root, "Parameters"))
+	t.conditions, _, _ = mappingNodes(mappingValue(root, "Conditions"))
+	if len(t.resources) == 0 {
+		return nil, fmt.Errorf("%s is not a CloudFormation template: no resources", path)
+	}
+	return t, nil
+}
+
+// location returns the logical ID with the template line, e.g. 'LogsBucket (template.yaml:12)'.
+func (t *template) location(name string, line int) string {
+	return fmt.Sprintf("%s (%s:%d)", name, t.file, line)
+}
+
+var resourceLocation = regexp.MustCompile(`^([A-Za-z0-9]+) \((.+):\d+\)$`)
+
+// ParseLocation returns the logical ID and template file of a resource location of a bucket state,
+// e.g. 'LogsBucket' and 'template.yaml' of 'LogsBucket (template.yaml:12)'; ok is false for other
+// resources, e.g. properties of a resource or Terraform addresses.
+func ParseLocation(resource string) (logicalID string, file string, ok bool) {
+	m := resourceLocation.FindStringSubmatch(resource)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+func (t *template) resourceType(logicalID string) string {
+	if resource := t.resources[logicalID]; resource != nil {
+		if node := mappingValue(resource, "Type"); node != nil {
+			return node.Value
+		}
+	}
+	return ""
+}
+
+// intrinsic returns the function name and argument of an intrinsic function in short ('!Sub') or
+// long form ('Fn::Sub').
+func intrinsic(node *yaml.Node) (string, *yaml.Node, bool) {
+	if strings.HasPrefix(node.Tag, "!") && !strings.HasPrefix(node.Tag, "!!") {
+		name := strings.TrimPrefix(node.Tag, "!")
+		if name != "Ref" && name != "Condition" {
+			name = "Fn::" + name
+		}
+		arg := *node
+		arg.Tag = ""
+		return name, &arg, true
+	}
+	if node.Kind == yaml.MappingNode && len(node.Content) == 2 {
+		key := node.Content[0].Value
+		if key == "Ref" || key == "Condition" || strings.HasPrefix(key, "Fn::") {
+			return key, node.Content[1], true
+		}
+	}
+	return "", nil, false
+}
+
+// value resolves a node to a Go value, evaluating intrinsic functions best-effort; values that
+// cannot be resolved without deploying the template are represented by placeholders like '${AWS::AccountId}'.
+func (t *template) value(node *yaml.Node) any {
+	if node == nil {
+		return nil
+	}
+	if node.Kind == yaml.AliasNode {
+		return t.value(node.Alias)
+	}
+	if name, arg, ok := intrinsic(node); ok {
+		return t.call(name, arg)
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		m := map[string]any{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if v := t.value(node.Content[i+1]); v != (noValue{}) {
+				m[node.Content[i].Value] = v
+			}
+		}
+		return m
+	case yaml.SequenceNode:
+		var list []any
+		for _, item := range node.Content {
+			if v := t.value(item); v != (noValue{}) {
+				list = append(list, v)
+			}
+		}
+		return list
+	default:
+		var v any
+		if err := node.Decode(&v); err != nil {
+			return node.Value
+		}
+		return v
+	}
+}
+
+func (t *template) strings(node *yaml.Node) []string {
+	list, _ := t.value(node).([]any)
+	var values []string
+	for _, item := range list {
+		values = append(values, toString(item))
+	}
+	return values
+}
+
+func toString(v any) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case nil, noValue:
+		return ""
+	case map[string]any, []any:
+		b, _ := json.Marshal(s)
+		return string(b)
+	default:
+		return fmt.Sprint(s)
+	}
+}
+
+func (t *template) call(name string, arg *yaml.Node) any {
+	args := arg.Content
+	switch name {
+	case "Ref":
+		return t.ref(toString(t.value(arg)))
+	case "Fn::GetAtt":
+		if arg.Kind == yaml.SequenceNode && len(args) == 2 {
+			return t.getAtt(toString(t.value(args[0])), toString(t.value(args[1])))
+		}
+		logicalID, attribute, _ := strings.Cut(toString(t.value(arg)), ".")
+		return t.getAtt(logicalID, attribute)
+	case "Fn::Sub":
+		if arg.Kind == yaml.SequenceNode && len(args) == 2 {
+			vars, _ := t.value(args[1]).(map[string]any)
+			return t.sub(toString(t.value(args[0])), vars)
+		}
+		return t.sub(toString(t.value(arg)), nil)
+	case "Fn::Join":
+		if len(args) == 2 {
+			return strings.Join(t.strings(args[1]), toString(t.value(args[0])))
+		}
+	case "Fn::Select":
+		if len(args) == 2 {
+			index, err := strconv.Atoi(toString(t.value(args[0])))
+			list, _ := t.value(args[1]).([]any)
+			if err == nil && index >= 0 && index < len(list) {
+				return list[index]
+			}
+		}
+	case "Fn::Split":
+		if len(args) == 2 {
+			var list []any
+			for _, s := range strings.Split(toString(t.value(args[1])), toString(t.value(args[0]))) {
+				list = append(list, s)
+			}
+			return list
+		}
+	case "Fn::FindInMap":
+		if len(args) == 3 {
+			mapping := mappingValue(mappingValue(mappingValue(t.mappings, toString(t.value(args[0]))),
+				toString(t.value(args[1]))), toString(t.value(args[2])))
+			if mapping != nil {
+				return t.value(mapping)
+			}
+		}
+	case "Fn::If":
+		if len(args) == 3 {
+			if t.condition(toString(t.value(args[0]))) {
+				return t.value(args[1])
+			}
+			return t.value(args[2])
+		}
+	case "Fn::Base64":
+		return t.value(arg)
+	case "Fn::ToJsonString":
+		return toString(t.value(arg))
+	}
+	log.Debugf("Could not resolve %s in %s line %d", name, t.file, arg.Line)
+	return placeholder(name)
+}
+
+func (t *template) ref(name string) any {
+	switch name {
+	case "AWS::NoValue":
+		return noValue{}
+	case "AWS::AccountId":
+		if t.options.AccountID != "" {
+			return t.options.AccountID
+		}
+	case "AWS::Region":
+		if t.options.Region != "" {
+			return t.options.Region
+		}
+	case "AWS::Partition":
+		return "aws"
+	case "AWS::URLSuffix":
+		return "amazonaws.com"
+	}
+
+	if parameter, ok := t.parameters[name]; ok {
+		if v, ok := t.options.Parameters[name]; ok {
+			return v
+		}
+		if d := mappingValue(parameter, "Default"); d != nil {
+			return t.value(d)
+		}
+		return placeholder(name)
+	}
+
+	// the bucket name is the only resource reference needed to audit buckets
+	if t.resourceType(name) == typeBucket {
+		return t.bucketName(name)
+	}
+	return placeholder(name)
+}
+
+func (t *template) getAtt(logicalID string, attribute string) any {
+	if t.resourceType(logicalID) == typeBucket && attribute == "Arn" {
+		return "arn:aws:s3:::" + t.bucketName(logicalID)
+	}
+	return placeholder(logicalID + "." + attribute)
+}
+
+var subVariable = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+func (t *template) sub(s string, vars map[string]any) string {
+	return subVariable.ReplaceAllStringFunc(s, func(match string) string {
+		name := match[2 : len(match)-1]
+		if literal, ok := strings.CutPrefix(name, "!"); ok {
+			return "${" + literal + "}"
+		}
+		if v, ok := vars[name]; ok {
+			return toString(v)
+		}
+		if logicalID, attribute, ok := strings.Cut(name, "."); ok && !strings.HasPrefix(name, "AWS::") {
+			return toString(t.getAtt(logicalID, attribute))
+		}
+		return toString(t.ref(name))
+	})
+}
+
+// condition evaluates a condition of the template; conditions that cannot be evaluated without
+// deploying the template, e.g. comparing a parameter without default, are considered true.
+func (t *template) condition(name string) bool {
+	value, known := t.conditionValue(name)
+	return value || !known
+}
+
+// conditionValue evaluates a condition; known is false if it cannot be evaluated.
+func (t *template) conditionValue(name string) (value bool, known bool) {
+	node, ok := t.conditions[name]
+	if !ok || t.resolving["Condition:"+name] {
+		return false, false
+	}
+	t.resolving["Condition:"+name] = true
+	defer delete(t.resolving, "Condition:"+name)
+	return t.evaluateCondition(node)
+}
+
+// evaluateCondition evaluates a condition function; a condition that cannot be evaluated stays unknown
+// through Fn::Not, so it is only assumed true for the resource or property it is used by.
+func (t *template) evaluateCondition(node *yaml.Node) (value bool, known bool) {
+	name, arg, ok := intrinsic(node)
+	if !ok {
+		return false, false
+	}
+	switch name {
+	case "Condition":
+		return t.conditionValue(toString(t.value(arg)))
+	case "Fn::Equals":
+		if len(arg.Content) == 2 {
+			a, b := toString(t.value(arg.Content[0])), toString(t.value(arg.Content[1]))
+			if strings.Contains(a, "${") || strings.Contains(b, "${") {
+				log.Debugf("Condition in %s line %d cannot be evaluated, assuming true", t.file, node.Line)
+				return false, false
+			}
+			return a == b, true
+		}
+	case "Fn::Not":
+		if len(arg.Content) == 1 {
+			value, known := t.evaluateCondition(arg.Content[0])
+			return !value, known
+		}
+	case "Fn::And":
+		known := true
+		for _, c := range arg.Content {
+			value, k := t.evaluateCondition(c)
+			if k && !value {
+				return false, true
+			}
+			known = known && k
+		}
+		return true, known
+	case "Fn::Or":
+		known := true
+		for _, c := range arg.Content {
+			value, k := t.evaluateCondition(c)
+			if k && value {
+				return true, true
+			}
+			known = known && k
+		}
+		return false, known
+	}
+	return false, false
+}
@@ -0,0 +1,164 @@
+// Package config reads the configuration file holding the audit settings.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/rollwagen/s3-cisbench/internal/audit"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of the configuration file searched in the current and the config directory.
+const FileName = ".s3-cisbench.yaml"
+
+// Config holds the audit settings; flags override them.
+type Config struct {
+	Debug            bool                               `yaml:"debug"`
+	Benchmark        audit.Benchmark                    `yaml:"benchmark"`  // CIS benchmark version, e.g. 'cis-2.0'
+	Framework        audit.Framework                    `yaml:"framework"`  // framework to label findings with, e.g. 'fsbp'
+	Controls         []audit.ControlID                  `yaml:"controls"`   // enabled controls; all controls if empty
+	Severities       map[audit.ControlID]audit.Severity `yaml:"severities"` // overrides of the controls' default severities
+	Filters          Filters                            `yaml:"filters"`
+	RequiredTags     []string                           `yaml:"requiredTags"`
+	AllowedTagValues map[string][]string                `yaml:"allowedTagValues"`
+	ObjectLock       *audit.ObjectLockRequirement       `yaml:"objectLock"`
+	Replication      *audit.ReplicationRequirement      `yaml:"replication"`
+	Profiles         []audit.Profile                    `yaml:"profiles"`
+	ProfilesFile     string                             `yaml:"profilesFile"`
+	Suppressions     []audit.Suppression                `yaml:"suppressions"`
+	SuppressionsFile string                             `yaml:"suppressionsFile"`
+	Rules            string                             `yaml:"rules"` // directory of custom rules
+	Output           Output                             `yaml:"output"`
+	AWS              AWS                                `yaml:"aws"`
+}
+
+// Filters select the audited buckets.
+type Filters struct {
+	Include        []string          `yaml:"include"` // name patterns, see audit.MatchName
+	Exclude        []string          `yaml:"exclude"`
+	Tags           map[string]string `yaml:"tags"`
+	ExcludeTags    map[string]string `yaml:"excludeTags"`
+	Regions        []string          `yaml:"regions"`
+	ExcludeRegions []string          `yaml:"excludeRegions"`
+}
+
+// Output configures where and in which format reports are written.
+type Output struct {
+	Format  string `yaml:"format"`  // txt, csv, json or noout
+	File    string `yaml:"file"`    // if set, csv and json reports are written to this file instead of stdout
+	Emit    string `yaml:"emit"`    // terraform, cloudformation or cli remediation instead of the report
+	History string `yaml:"history"` // history file audit runs are recorded in
+}
+
+// AWS configures the AWS SDK and S3 compatible endpoints.
+type AWS struct {
+	Profile     string `yaml:"profile"` // shared config profile, unless AWS_PROFILE is set
+	Region      string `yaml:"region"`  // default region, unless AWS_REGION is set
+	EndpointURL string `yaml:"endpointUrl"`
+	PathStyle   bool   `yaml:"pathStyle"`
+	NoVerifySSL bool   `yaml:"noVerifySsl"`
+}
+
+// Error is an error at a line of the configuration file; the line is 0 if unknown.
+type Error struct {
+	Line    int
+	Message string
+}
+
+func (e Error) Error() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// Find returns the path of the configuration file in the current directory or else in $XDG_CONFIG_HOME
+// (default ~/.config); it is empty if there is none.
+func Find() string {
+	var dirs []string
+	if cwd, err := os.Getwd(); err == nil {
+		dirs = append(dirs, cwd)
+	}
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
+		dirs = append(dirs, configHome)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".config"))
+	}
+
+	for _, dir := range dirs {
+		path := filepath.Join(dir, FileName)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// Load reads and validates the configuration file; the error joins all errors found.
+func Load(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, errs := Parse(b, filepath.Dir(path))
+	if len(errs) != 0 {
+		joined := make([]error, len(errs))
+		for i, e := range errs {
+			joined[i] = e
+		}
+		return nil, fmt.Errorf("invalid configuration file %s:\n%w", path, errors.Join(joined...))
+	}
+	return config, nil
+}
+
+var yamlLinePattern = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// yamlErrors converts the errors of the YAML decoder into errors with line numbers.
+func yamlErrors(err error) []Error {
+	var messages []string
+	var typeError *yaml.TypeError
+	if errors.As(err, &typeError) {
+		messages = typeError.Errors
+	} else {
+		messages = []string{err.Error()}
+	}
+
+	var errs []Error
+	for _, message := range messages {
+		if m := yamlLinePattern.FindStringSubmatch(message); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			errs = append(errs, Error{Line: line, Message: m[2]})
+		} else {
+			errs = append(errs, Error{Message: message})
+		}
+	}
+	return errs
+}
+
+// Parse decodes and validates a configuration; it returns all errors found, e.g. unknown fields
+// or controls, with their line. Relative paths of referenced files are resolved against dir.
+func Parse(b []byte, dir string) (*Config, []Error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(b, &root); err != nil {
+		return nil, yamlErrors(err)
+	}
+
+	config := &Config{}
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, yamlErrors(err)
+	}
+
+	if errs := config.validate(&document{root: &root, dir: dir}); len(errs) != 0 {
+		return nil, errs
+	}
+	return config, nil
+}
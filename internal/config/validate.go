@@ -0,0 +1,139 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/rollwagen/s3-cisbench/internal/audit"
+	"github.com/rollwagen/s3-cisbench/internal/remediation"
+	"gopkg.in/yaml.v3"
+)
+
+var outputFormats = []string{"txt", "csv", "json", "noout"}
+
+// document locates values of the configuration file to report errors with their line.
+type document struct {
+	root *yaml.Node
+	dir  string // directory of the configuration file; relative file paths are resolved against it
+}
+
+// line returns the line of the value at the path of mapping keys and sequence indexes, or of its
+// closest parent if the path does not exist.
+func (d *document) line(path ...any) int {
+	node := d.root
+	if node.Kind == yaml.DocumentNode && len(node.Content) != 0 {
+		node = node.Content[0]
+	}
+	for _, element := range path {
+		var next *yaml.Node
+		switch e := element.(type) {
+		case string:
+			for i := 0; node.Kind == yaml.MappingNode && i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == e {
+					next = node.Content[i+1]
+				}
+			}
+		case int:
+			if node.Kind == yaml.SequenceNode && e < len(node.Content) {
+				next = node.Content[e]
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return node.Line
+}
+
+// resolve returns the path relative to the configuration file's directory.
+func (d *document) resolve(path string) string {
+	if path == "" || filepath.IsAbs(path) || d.dir == "" {
+		return path
+	}
+	return filepath.Join(d.dir, path)
+}
+
+// validate checks the values the YAML schema cannot, e.g. control IDs, and resolves the referenced files.
+func (c *Config) validate(d *document) []Error {
+	var errs []Error
+	add := func(err error, path ...any) {
+		if err != nil {
+			errs = append(errs, Error{Line: d.line(path...), Message: err.Error()})
+		}
+	}
+	knownControl := func(id audit.ControlID) error {
+		if !audit.IsValidControl(id) {
+			return fmt.Errorf("unknown control '%s'", id)
+		}
+		return nil
+	}
+
+	add(audit.ValidateBenchmark(c.Benchmark), "benchmark")
+	add(audit.ValidateFramework(c.Framework), "framework")
+	for i, id := range c.Controls {
+		add(knownControl(id), "controls", i)
+	}
+	for id, severity := range c.Severities {
+		add(knownControl(id), "severities", string(id))
+		if !severity.IsValid() {
+			add(fmt.Errorf("invalid severity '%s' of control '%s': expected LOW, MEDIUM, HIGH or CRITICAL", severity, id),
+				"severities", string(id))
+		}
+	}
+
+	for _, key := range []string{"include", "exclude"} {
+		patterns := c.Filters.Include
+		if key == "exclude" {
+			patterns = c.Filters.Exclude
+		}
+		for i, pattern := range patterns {
+			add(audit.ValidatePatterns([]string{pattern}), "filters", key, i)
+		}
+	}
+
+	if c.ObjectLock != nil {
+		add(audit.ValidateObjectLockMode(c.ObjectLock.Mode), "objectLock", "mode")
+		add(audit.ValidatePatterns(c.ObjectLock.Selector.Names), "objectLock", "selector", "names")
+	}
+	if c.Replication != nil {
+		add(audit.ValidatePatterns(c.Replication.Selector.Names), "replication", "selector", "names")
+	}
+
+	for i := range c.Profiles {
+		add(c.Profiles[i].Validate(), "profiles", i)
+	}
+	if c.ProfilesFile != "" {
+		c.ProfilesFile = d.resolve(c.ProfilesFile)
+		_, err := audit.LoadProfiles(c.ProfilesFile)
+		add(err, "profilesFile")
+	}
+	for i := range c.Suppressions {
+		add(c.Suppressions[i].Validate(), "suppressions", i)
+	}
+	if c.SuppressionsFile != "" {
+		c.SuppressionsFile = d.resolve(c.SuppressionsFile)
+		_, err := audit.LoadSuppressions(c.SuppressionsFile)
+		add(err, "suppressionsFile")
+	}
+	if c.Rules != "" {
+		c.Rules = d.resolve(c.Rules)
+		_, err := audit.LoadRules(c.Rules)
+		add(err, "rules")
+	}
+
+	if c.Output.Format != "" && !slices.Contains(outputFormats, c.Output.Format) {
+		add(fmt.Errorf("invalid output format '%s': expected txt, csv, json or noout", c.Output.Format), "output", "format")
+	}
+	if c.Output.File != "" && (c.Output.Format == "" || c.Output.Format == "txt") {
+		add(fmt.Errorf("output file requires format csv or json"), "output", "file")
+	}
+	if c.Output.Emit != "" {
+		_, err := remediation.NewEmitter(c.Output.Emit)
+		add(err, "output", "emit")
+	}
+
+	slices.SortFunc(errs, func(a, b Error) int { return a.Line - b.Line })
+	return errs
+}
@@ -0,0 +1,118 @@
+// Package diff compares the JSON reports of two audit runs.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/rollwagen/s3-cisbench/internal/audit"
+)
+
+// Bucket identifies a bucket of a report; buckets are matched by account, region and name.
+type Bucket struct {
+	AccountID string `json:"accountId"`
+	Region    string `json:"region"`
+	Name      string `json:"name"`
+}
+
+func (b Bucket) String() string {
+	return fmt.Sprintf("%s/%s/%s", b.AccountID, b.Region, b.Name)
+}
+
+// BucketChange is a bucket only present in one of the reports, with its failed controls.
+type BucketChange struct {
+	Bucket
+	Failed []audit.ControlID `json:"failed,omitempty"`
+}
+
+// ControlChange is a control of a bucket whose status changed between the reports.
+type ControlChange struct {
+	Bucket
+	Control   audit.ControlID `json:"control"`
+	OldStatus audit.Status    `json:"oldStatus,omitempty"` // empty if the control was not evaluated
+	NewStatus audit.Status    `json:"newStatus,omitempty"` // empty if the control is no longer evaluated
+	Message   string          `json:"message"`             // message of the new finding, or else of the old finding
+}
+
+// Diff holds the changes from an old to a new report.
+type Diff struct {
+	NewBuckets     []BucketChange  `json:"newBuckets"`
+	DeletedBuckets []BucketChange  `json:"deletedBuckets"`
+	Regressed      []ControlChange `json:"regressed"` // controls now failing that did not fail before
+	Fixed          []ControlChange `json:"fixed"`     // controls that failed before and now pass
+}
+
+// IsEmpty reports whether the reports do not differ in buckets or failed controls.
+func (d Diff) IsEmpty() bool {
+	return len(d.NewBuckets) == 0 && len(d.DeletedBuckets) == 0 && len(d.Regressed) == 0 && len(d.Fixed) == 0
+}
+
+// LoadReport reads a report written by the JSON printer.
+func LoadReport(path string) ([]audit.BucketReport, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var reports []audit.BucketReport
+	if err := json.Unmarshal(b, &reports); err != nil {
+		return nil, fmt.Errorf("could not parse JSON report %s: %v", path, err)
+	}
+	return reports, nil
+}
+
+func bucketOf(r audit.BucketReport) Bucket {
+	return Bucket{AccountID: r.AccountID, Region: r.Region, Name: r.Name}
+}
+
+func failedControls(r audit.BucketReport) []audit.ControlID {
+	var failed []audit.ControlID
+	for _, f := range r.Findings {
+		if f.Status == audit.StatusFail {
+			failed = append(failed, f.Control)
+		}
+	}
+	return failed
+}
+
+// Compare returns the changes from the old to the new report, in the order of the new report's buckets
+// and of the controls; deleted buckets are in the order of the old report.
+func Compare(oldReports []audit.BucketReport, newReports []audit.BucketReport) Diff {
+	d := Diff{NewBuckets: []BucketChange{}, DeletedBuckets: []BucketChange{}, Regressed: []ControlChange{}, Fixed: []ControlChange{}}
+	old := map[Bucket]audit.BucketReport{}
+	for _, r := range oldReports {
+		old[bucketOf(r)] = r
+	}
+	current := map[Bucket]bool{}
+
+	for _, r := range newReports {
+		bucket := bucketOf(r)
+		current[bucket] = true
+		oldReport, ok := old[bucket]
+		if !ok {
+			d.NewBuckets = append(d.NewBuckets, BucketChange{Bucket: bucket, Failed: failedControls(r)})
+			continue
+		}
+
+		for _, control := range audit.ReportControls(oldReport, r) {
+			oldFinding, oldOK := oldReport.Finding(control)
+			newFinding, newOK := r.Finding(control)
+			change := ControlChange{Bucket: bucket, Control: control, OldStatus: oldFinding.Status, NewStatus: newFinding.Status}
+			switch {
+			case newOK && newFinding.Status == audit.StatusFail && (!oldOK || oldFinding.Status != audit.StatusFail):
+				change.Message = newFinding.Message
+				d.Regressed = append(d.Regressed, change)
+			case oldOK && oldFinding.Status == audit.StatusFail && newOK && newFinding.Status == audit.StatusPass:
+				change.Message = newFinding.Message
+				d.Fixed = append(d.Fixed, change)
+			}
+		}
+	}
+
+	for _, r := range oldReports {
+		if bucket := bucketOf(r); !current[bucket] {
+			d.DeletedBuckets = append(d.DeletedBuckets, BucketChange{Bucket: bucket, Failed: failedControls(r)})
+		}
+	}
+	return d
+}
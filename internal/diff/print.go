@@ -0,0 +1,113 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/rollwagen/s3-cisbench/internal/audit"
+)
+
+func joinControls(ids []audit.ControlID) string {
+	var s []string
+	for _, id := range ids {
+		s = append(s, string(id))
+	}
+	return strings.Join(s, ", ")
+}
+
+func statusOrNone(s audit.Status) string {
+	if s == "" {
+		return "-"
+	}
+	return string(s)
+}
+
+// WriteText writes the diff as colored text for the terminal.
+func (d Diff) WriteText(w io.Writer) error {
+	if d.IsEmpty() {
+		_, err := fmt.Fprintln(w, "No changes")
+		return err
+	}
+
+	heading := color.New(color.FgHiBlue).Add(color.Bold)
+	bucketSection := func(title string, glyph string, c *color.Color, buckets []BucketChange) {
+		if len(buckets) == 0 {
+			return
+		}
+		_, _ = heading.Fprintf(w, "\n %s (%d)\n", title, len(buckets))
+		for _, b := range buckets {
+			_, _ = c.Fprintf(w, "\t%s %s", glyph, b.Bucket)
+			if len(b.Failed) != 0 {
+				_, _ = fmt.Fprintf(w, " (%d failed: %s)", len(b.Failed), joinControls(b.Failed))
+			}
+			_, _ = fmt.Fprintln(w)
+		}
+	}
+	controlSection := func(title string, glyph string, c *color.Color, changes []ControlChange) {
+		if len(changes) == 0 {
+			return
+		}
+		_, _ = heading.Fprintf(w, "\n %s (%d)\n", title, len(changes))
+		for _, change := range changes {
+			_, _ = c.Fprintf(w, "\t%s %s: %s", glyph, change.Bucket, change.Control)
+			_, _ = fmt.Fprintf(w, " %s → %s: %s\n", statusOrNone(change.OldStatus), statusOrNone(change.NewStatus), change.Message)
+		}
+	}
+
+	bucketSection("New buckets", "+", color.New(color.FgHiCyan), d.NewBuckets)
+	bucketSection("Deleted buckets", "-", color.New(color.FgHiYellow), d.DeletedBuckets)
+	controlSection("Regressed controls", "✖", color.New(color.FgHiRed), d.Regressed)
+	controlSection("Fixed controls", "✔", color.New(color.FgHiGreen), d.Fixed)
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// markdownCell escapes the characters breaking a Markdown table cell.
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+// WriteMarkdown writes the diff as Markdown tables, e.g. for a pull request or ticket comment.
+func (d Diff) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("## S3 audit changes\n")
+	if d.IsEmpty() {
+		b.WriteString("\nNo changes\n")
+	}
+
+	for _, section := range []struct {
+		title   string
+		buckets []BucketChange
+	}{{"New buckets", d.NewBuckets}, {"Deleted buckets", d.DeletedBuckets}} {
+		if len(section.buckets) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### %s (%d)\n\n", section.title, len(section.buckets))
+		b.WriteString("| Account | Region | Bucket | Failed controls |\n|---|---|---|---|\n")
+		for _, bucket := range section.buckets {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", markdownCell(bucket.AccountID), markdownCell(bucket.Region),
+				markdownCell(bucket.Name), markdownCell(joinControls(bucket.Failed)))
+		}
+	}
+
+	for _, section := range []struct {
+		title   string
+		changes []ControlChange
+	}{{"Regressed controls", d.Regressed}, {"Fixed controls", d.Fixed}} {
+		if len(section.changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### %s (%d)\n\n", section.title, len(section.changes))
+		b.WriteString("| Account | Region | Bucket | Control | Old | New | Message |\n|---|---|---|---|---|---|---|\n")
+		for _, change := range section.changes {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s |\n", markdownCell(change.AccountID), markdownCell(change.Region),
+				markdownCell(change.Name), change.Control, statusOrNone(change.OldStatus), statusOrNone(change.NewStatus),
+				markdownCell(change.Message))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
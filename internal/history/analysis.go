@@ -0,0 +1,156 @@
+package history
+
+import (
+	"sort"
+	"time"
+
+	"github.com/rollwagen/s3-cisbench/internal/audit"
+)
+
+// ControlHistory is the status of a bucket's control in each run; the status is empty for runs
+// that did not audit the bucket or evaluate the control.
+type ControlHistory struct {
+	Control  audit.ControlID `json:"control"`
+	Statuses []audit.Status  `json:"statuses"`
+}
+
+// BucketHistory returns the status of each control of the bucket over the runs. If accountID is empty,
+// buckets with the name in any account match.
+func BucketHistory(runs []Run, accountID string, name string) []ControlHistory {
+	var reports []audit.BucketReport
+	for _, run := range runs {
+		reports = append(reports, run.Reports...)
+	}
+
+	var history []ControlHistory
+	for _, control := range audit.ReportControls(reports...) {
+		h := ControlHistory{Control: control, Statuses: make([]audit.Status, len(runs))}
+		evaluated := false
+		for i, run := range runs {
+			for _, report := range run.Reports {
+				if report.Name != name || (accountID != "" && report.AccountID != accountID) {
+					continue
+				}
+				if f, ok := report.Finding(control); ok {
+					h.Statuses[i] = f.Status
+					evaluated = true
+				}
+			}
+		}
+		if evaluated {
+			history = append(history, h)
+		}
+	}
+	return history
+}
+
+// Remediation is a failed finding of a bucket and, if it was fixed, when.
+type Remediation struct {
+	AccountID   string          `json:"accountId"`
+	Region      string          `json:"region"`
+	Bucket      string          `json:"bucket"`
+	Control     audit.ControlID `json:"control"`
+	FailedSince time.Time       `json:"failedSince"` // time of the first run the finding failed in
+	FixedAt     *time.Time      `json:"fixedAt,omitempty"`
+}
+
+// Duration returns the time to remediate, or for open findings the time failed until now.
+func (r Remediation) Duration(now time.Time) time.Duration {
+	if r.FixedAt != nil {
+		return r.FixedAt.Sub(r.FailedSince)
+	}
+	return now.Sub(r.FailedSince)
+}
+
+type findingKey struct {
+	accountID, region, bucket string
+	control                   audit.ControlID
+}
+
+// TimeToRemediate returns the findings that failed and were fixed in a later run, followed by the findings
+// still failing in the latest run. A finding that fails again after being fixed is counted again.
+func TimeToRemediate(runs []Run) []Remediation {
+	var remediations []Remediation
+	open := map[findingKey]Remediation{}
+
+	for _, run := range runs {
+		for _, report := range run.Reports {
+			for _, f := range report.Findings {
+				key := findingKey{report.AccountID, report.Region, report.Name, f.Control}
+				r, failing := open[key]
+				switch {
+				case f.Status == audit.StatusFail && !failing:
+					open[key] = Remediation{AccountID: report.AccountID, Region: report.Region, Bucket: report.Name,
+						Control: f.Control, FailedSince: run.Time}
+				case f.Status == audit.StatusPass && failing:
+					fixedAt := run.Time
+					r.FixedAt = &fixedAt
+					remediations = append(remediations, r)
+					delete(open, key)
+				}
+			}
+		}
+	}
+
+	if len(runs) == 0 {
+		return remediations
+	}
+	// findings of buckets no longer audited, e.g. deleted ones, are dropped
+	var stillOpen []Remediation
+	for _, report := range runs[len(runs)-1].Reports {
+		for _, f := range report.Findings {
+			if r, ok := open[findingKey{report.AccountID, report.Region, report.Name, f.Control}]; ok && f.Status == audit.StatusFail {
+				stillOpen = append(stillOpen, r)
+			}
+		}
+	}
+	return append(remediations, stillOpen...)
+}
+
+// ControlSummary aggregates the time to remediate the findings of a control.
+type ControlSummary struct {
+	Control audit.ControlID `json:"control"`
+	Fixed   int             `json:"fixed"`
+	Open    int             `json:"open"`
+	Mean    time.Duration   `json:"meanNanoseconds"`   // mean time to remediate of the fixed findings
+	Median  time.Duration   `json:"medianNanoseconds"` // median time to remediate of the fixed findings
+}
+
+// Summarize aggregates the remediations per control, in the order of the controls.
+func Summarize(remediations []Remediation) []ControlSummary {
+	var controls []audit.ControlID
+	for _, r := range remediations {
+		controls = append(controls, r.Control)
+	}
+
+	var summaries []ControlSummary
+	for _, control := range audit.SortControls(controls) {
+		summary := ControlSummary{Control: control}
+		var durations []time.Duration
+		var total time.Duration
+		for _, r := range remediations {
+			switch {
+			case r.Control != control:
+			case r.FixedAt == nil:
+				summary.Open++
+			default:
+				summary.Fixed++
+				durations = append(durations, r.Duration(*r.FixedAt))
+				total += r.Duration(*r.FixedAt)
+			}
+		}
+		if summary.Fixed == 0 && summary.Open == 0 {
+			continue
+		}
+		if len(durations) != 0 {
+			sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+			summary.Mean = total / time.Duration(len(durations))
+			summary.Median = durations[len(durations)/2]
+			if len(durations)%2 == 0 {
+				summary.Median = (durations[len(durations)/2-1] + durations[len(durations)/2]) / 2
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
@@ -0,0 +1,101 @@
+// Package history records audit runs in a local embedded store for trend reporting.
+package history
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rollwagen/s3-cisbench/internal/audit"
+	bolt "go.etcd.io/bbolt"
+)
+
+var runsBucket = []byte("runs")
+
+// Run is a recorded audit run.
+type Run struct {
+	ID       uint64               `json:"id"`
+	Time     time.Time            `json:"time"`
+	Identity string               `json:"identity,omitempty"` // ARN of the identity the audit ran as
+	Reports  []audit.BucketReport `json:"reports"`
+}
+
+// Failed returns the number of failed findings of the run.
+func (r Run) Failed() int {
+	failed := 0
+	for _, report := range r.Reports {
+		for _, f := range report.Findings {
+			if f.Status == audit.StatusFail {
+				failed++
+			}
+		}
+	}
+	return failed
+}
+
+// Store is a bbolt file holding the recorded runs.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens the store at path; unless read only, it is created if it does not exist.
+func Open(path string, readOnly bool) (*Store, error) {
+	if _, err := os.Stat(path); readOnly && err != nil {
+		return nil, fmt.Errorf("could not open history %s: %v", path, err)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second, ReadOnly: readOnly})
+	if err != nil {
+		return nil, fmt.Errorf("could not open history %s: %v", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func runKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id) // big endian keys iterate in run order
+	return key
+}
+
+// Record stores the run and sets its ID.
+func (s *Store) Record(run *Run) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		runs, err := tx.CreateBucketIfNotExists(runsBucket)
+		if err != nil {
+			return err
+		}
+		if run.ID, err = runs.NextSequence(); err != nil {
+			return err
+		}
+		value, err := json.Marshal(run)
+		if err != nil {
+			return err
+		}
+		return runs.Put(runKey(run.ID), value)
+	})
+}
+
+// Runs returns all recorded runs in the order they were recorded.
+func (s *Store) Runs() ([]Run, error) {
+	var runs []Run
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(runsBucket)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(key []byte, value []byte) error {
+			var run Run
+			if err := json.Unmarshal(value, &run); err != nil {
+				return fmt.Errorf("could not parse run %d: %v", binary.BigEndian.Uint64(key), err)
+			}
+			runs = append(runs, run)
+			return nil
+		})
+	})
+	return runs, err
+}
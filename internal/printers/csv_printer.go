@@ -3,7 +3,9 @@ package printers
 import (
 	"encoding/csv"
 	"io"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/rollwagen/s3-cisbench/internal/audit"
 )
@@ -12,20 +14,53 @@ type CSVPrinter struct{}
 
 func (r *CSVPrinter) PrintReport(reports []audit.BucketReport, w io.Writer) error {
 	csvWriter := csv.NewWriter(w)
+
+	// one column per tag key found on any bucket, so the output can be pivoted by tags
+	tagKeys := map[string]string{}
+	for _, r := range reports {
+		for key := range r.Tags {
+			tagKeys[key] = key
+		}
+	}
+	sortedTagKeys := sortedKeys(tagKeys)
+
 	var data [][]string
-	data = append(data, []string{
+	header := []string{
 		"Account Id",
 		"Region",
 		"Bucket Name",
-		"Server Side Encryption",
+		cisColumn(reports, "Server Side Encryption", audit.ControlEncryption),
 		"Versioning enabled",
-		"MFA delete",
-		"Deny HTTP only",
-		"Block Public ACLs",
-		"Ignore Public ACLs",
-		"Block Public Policy",
-		"Restrict Public Buckets",
-	})
+		cisColumn(reports, "MFA delete", audit.ControlMFADelete),
+		cisColumn(reports, "Deny HTTP only", audit.ControlDenyHTTP),
+		"Policy TLS 1.2",
+		"Policy public access",
+		"Policy cross-account access",
+		cisColumn(reports, "Block Public ACLs", audit.ControlBlockPublicAccess),
+		cisColumn(reports, "Ignore Public ACLs", audit.ControlBlockPublicAccess),
+		cisColumn(reports, "Block Public Policy", audit.ControlBlockPublicAccess),
+		cisColumn(reports, "Restrict Public Buckets", audit.ControlBlockPublicAccess),
+		"Object Lock enabled",
+		"Object Lock mode",
+		"Object Lock retention days",
+		"Lifecycle rules",
+		"Noncurrent version expiration",
+		"Abort incomplete multipart upload",
+		"Replication destinations",
+		"Website endpoint",
+		"CORS any origin",
+		"CORS write methods",
+		"EventBridge notifications",
+		"Notification destinations",
+		"Profile",
+		"Suppressed controls",
+		"Failed requirements",
+		"Failed custom rules",
+	}
+	for _, key := range sortedTagKeys {
+		header = append(header, "Tag: "+key)
+	}
+	data = append(data, header)
 	for _, r := range reports {
 		bpa := r.BlockPublicAccess
 		row := []string{
@@ -36,10 +71,32 @@ func (r *CSVPrinter) PrintReport(reports []audit.BucketReport, w io.Writer) erro
 			strconv.FormatBool(r.VersioningEnabled),
 			strconv.FormatBool(r.MFADelete),
 			strconv.FormatBool(r.PolicyDenyHTTP),
+			findingStatus(r, audit.ControlPolicyTLSVersion),
+			findingStatus(r, audit.ControlPolicyPublic),
+			findingStatus(r, audit.ControlPolicyCrossAccount),
 			strconv.FormatBool(bpa.BlockPublicAcls),
 			strconv.FormatBool(bpa.IgnorePublicAcls),
 			strconv.FormatBool(bpa.BlockPublicPolicy),
 			strconv.FormatBool(bpa.RestrictPublicBuckets),
+			strconv.FormatBool(r.ObjectLock.Enabled),
+			r.ObjectLock.Mode,
+			strconv.FormatInt(int64(r.ObjectLock.RetentionDays), 10),
+			strconv.Itoa(len(r.Lifecycle.Rules)),
+			strconv.FormatBool(r.Lifecycle.NoncurrentVersionExpiration),
+			strconv.FormatBool(r.Lifecycle.AbortIncompleteMultipartUpload),
+			replicationDestinations(r.Replication),
+			r.Website.Endpoint,
+			strconv.FormatBool(corsAny(r.CORS, func(rule audit.CORSRuleReport) bool { return rule.WildcardOrigin })),
+			strconv.FormatBool(corsAny(r.CORS, func(rule audit.CORSRuleReport) bool { return rule.WriteMethods })),
+			strconv.FormatBool(r.Notification.EventBridgeEnabled),
+			notificationDestinations(r.Notification),
+			r.Profile,
+			suppressedControls(r),
+			failedRequirements(r),
+			failedCustomRules(r),
+		}
+		for _, key := range sortedTagKeys {
+			row = append(row, r.Tags[key])
 		}
 		data = append(data, row)
 	}
@@ -47,3 +104,83 @@ func (r *CSVPrinter) PrintReport(reports []audit.BucketReport, w io.Writer) erro
 
 	return nil
 }
+
+// cisColumn appends the CIS benchmark item of the control, as cited by the reports' findings, to the column name.
+func cisColumn(reports []audit.BucketReport, name string, id audit.ControlID) string {
+	for _, r := range reports {
+		if f, ok := r.Finding(id); ok && f.Benchmark != nil {
+			return name + " (CIS " + f.Benchmark.Item + ")"
+		}
+	}
+	return name
+}
+
+func findingStatus(report audit.BucketReport, id audit.ControlID) string {
+	if finding, ok := report.Finding(id); ok {
+		return string(finding.Status)
+	}
+	return ""
+}
+
+func suppressedControls(report audit.BucketReport) string {
+	var controls []string
+	for _, f := range report.Findings {
+		if f.Suppressed() {
+			controls = append(controls, string(f.Control))
+		}
+	}
+	return strings.Join(controls, " ")
+}
+
+// failedRequirements returns the requirements of the selected framework evaluated by failed controls.
+func failedRequirements(report audit.BucketReport) string {
+	var requirements []string
+	for _, f := range report.Findings {
+		if f.Status != audit.StatusFail || f.Suppressed() {
+			continue
+		}
+		for _, id := range f.Requirements {
+			if !slices.Contains(requirements, id) {
+				requirements = append(requirements, id)
+			}
+		}
+	}
+	return strings.Join(requirements, " ")
+}
+
+func failedCustomRules(report audit.BucketReport) string {
+	var controls []string
+	for _, f := range report.Findings {
+		if audit.IsCustomControl(f.Control) && f.Status == audit.StatusFail && !f.Suppressed() {
+			controls = append(controls, string(f.Control))
+		}
+	}
+	return strings.Join(controls, " ")
+}
+
+func corsAny(cors audit.CORSReport, matches func(audit.CORSRuleReport) bool) bool {
+	for _, rule := range cors.Rules {
+		if matches(rule) {
+			return true
+		}
+	}
+	return false
+}
+
+func notificationDestinations(notification audit.NotificationReport) string {
+	var destinations []string
+	for _, d := range notification.Destinations {
+		destinations = append(destinations, d.ARN)
+	}
+	return strings.Join(destinations, " ")
+}
+
+func replicationDestinations(replication audit.ReplicationReport) string {
+	var destinations []string
+	for _, rule := range replication.Rules {
+		if rule.Enabled {
+			destinations = append(destinations, rule.DestinationAccount+"/"+rule.DestinationRegion+"/"+rule.DestinationBucket)
+		}
+	}
+	return strings.Join(destinations, " ")
+}
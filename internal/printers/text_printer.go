@@ -5,12 +5,17 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/rollwagen/s3-cisbench/internal/audit"
 )
 
-type TextPrinter struct{}
+type TextPrinter struct {
+	Framework audit.Framework // if set, findings are labelled and summarized by the framework's requirements
+}
 
 func (r *TextPrinter) PrintReport(report []audit.BucketReport, w io.Writer) error {
 	if w != os.Stdout {
@@ -39,132 +44,249 @@ func (r *TextPrinter) PrintReport(report []audit.BucketReport, w io.Writer) erro
 
 		// Bucket name
 		colorBucketPrintln(" \uE703 " + b.Name)
+		if b.Resource != "" && b.Resource != b.Name {
+			colorBucketPrint(" " + GlyphHDotted)
+			_, _ = color.New(color.FgWhite).Println("\t\uf121 " + b.Resource)
+		}
+		if b.Profile != "" {
+			colorBucketPrint(" " + GlyphHDotted)
+			_, _ = color.New(color.FgWhite).Println("\t\uf0ae Profile: " + b.Profile)
+		}
+		if len(b.Tags) != 0 {
+			colorBucketPrint(" " + GlyphHDotted)
+			_, _ = color.New(color.FgWhite).Println("\t\uf02c " + formatTags(b.Tags))
+		}
 
-		// CIS 2.1.1 - ServerSideEncryptionEnabled
-		colorBucketPrint(" " + GlyphHDotted)
-		cCIS := color.New(color.FgHiCyan)
-		_, _ = cCIS.Println("\tEnsure all S3 buckets employ encryption-at-rest [CIS 2.1.1]")
-
-		colorBucketPrint(" " + GlyphHDotted)
-		if b.ServerSideEncryptionEnabled {
-			// for _, rule := range encryptionOutput.ServerSideEncryptionConfiguration.Rules {
-			//	if rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm == "AES256" {
-			c := color.New(color.FgHiGreen).Add(color.Bold)
-			_, _ = c.Print("\t\t󰞚 ")
-			// _, _ = c.Print("\t\t\uf046 ")
-			c = color.New(color.FgGreen)
-			// _, _ = cBucket.Println(" Server side encryption with AES256 is enabled")
-			if b.CustomerManagedKey {
-				_, _ = c.Println(" Server side encryption is enabled with customer managed 󰌋")
-			} else {
-				_, _ = c.Println(" Server side encryption is enabled")
+		for _, id := range textControls(b) {
+			finding, _ := b.Finding(id)
+			control, ok := audit.LookupControl(id)
+			if !ok {
+				control = audit.Control{ID: id, Title: finding.Title}
 			}
-		} else {
-			c := color.New(color.FgHiRed).Add(color.Bold)
-			_, _ = c.Print("\t\t󰉀") // cBucket.Print("\t\t\uf071")
-			c = color.New(color.FgRed)
-			_, _ = c.Println(" No server side encryption found")
-		}
 
-		// CIS 2.1.2 - Ensure S3 Bucket Policy is set to deny HTTP requests
-		colorBucketPrint(" " + GlyphHDotted)
-		cCIS = color.New(color.FgHiCyan)
-		_, _ = cCIS.Println("\tEnsure S3 Bucket Policy is set to deny HTTP requests [CIS 2.1.2]")
-		colorBucketPrint(" " + GlyphHDotted)
-		if b.PolicyDenyHTTP {
-			c := color.New(color.FgHiGreen).Add(color.Bold)
-			_, _ = c.Print("\t\t\uf023 ")
-			c = color.New(color.FgGreen)
-			_, _ = c.Println(" Bucket policy to deny HTTP requests is present")
-		} else {
-			c := color.New(color.FgHiRed).Add(color.Bold)
-			_, _ = c.Print("\t\t\uf09c") // cBucket.Print("\t\t\uf071")
-			c = color.New(color.FgRed)
-			_, _ = c.Println(" No Bucket policy to deny HTTP requests found")
-		}
+			colorBucketPrint(" " + GlyphHDotted)
+			cCIS := color.New(color.FgHiCyan)
+			_, _ = cCIS.Println("\t" + controlTitle(control, finding) + r.requirementLabel(finding))
+
+			colorBucketPrint(" " + GlyphHDotted)
+			glyphs := controlGlyphs(control.ID)
+			bpa := control.ID == audit.ControlBlockPublicAccess && finding.Status != audit.StatusNotApplicable
+			switch {
+			case bpa:
+				printBlockPublicAccess(finding, func() { colorBucketPrint(" " + GlyphHDotted) })
+			case finding.Status == audit.StatusPass:
+				printPass(glyphs.pass, " "+finding.Message)
+			case finding.Status == audit.StatusFail:
+				if finding.Suppressed() {
+					c := color.New(color.FgHiMagenta)
+					_, _ = c.Print("\t\t\uf070")
+					_, _ = c.Println(" " + finding.Message + " [" + string(finding.Severity) + ", SUPPRESSED]")
+				} else {
+					printFail(glyphs.fail, " "+finding.Message+" ["+string(finding.Severity)+"]")
+				}
+			default:
+				c := color.New(color.FgHiYellow)
+				_, _ = c.Print("\t\t-")
+				_, _ = c.Println(" " + finding.Message)
+			}
 
-		// Non CIS - Versioning enabled
-		colorBucketPrint(" " + GlyphHDotted)
-		_, _ = cCIS.Println("\tS3 bucket versioning enabled (non-CIS)")
-
-		colorBucketPrint(" " + GlyphHDotted)
-		if b.VersioningEnabled {
-			c := color.New(color.FgHiGreen).Add(color.Bold)
-			_, _ = c.Print("\t\t\uf454 ")
-			c = color.New(color.FgGreen)
-			_, _ = c.Println(" S3 bucket has versioning enabled")
-		} else {
-			c := color.New(color.FgHiRed).Add(color.Bold)
-			_, _ = c.Print("\t\t󰉀") // cBucket.Print("\t\t\uf071")
-			c = color.New(color.FgRed)
-			_, _ = c.Println(" Versioning is not enabled")
+			if suppression := finding.Suppression; suppression != nil && finding.Status == audit.StatusFail {
+				colorBucketPrint(" " + GlyphHDotted)
+				if suppression.Expired {
+					_, _ = color.New(color.FgRed).Println("\t\t\uf071 Suppression expired on " + suppression.Expires + ": " + suppression.Justification)
+				} else {
+					_, _ = color.New(color.FgMagenta).Println("\t\t\uf05a Suppressed until " + suppression.Expires + ": " + suppression.Justification)
+				}
+			}
+			if finding.Resource != "" && finding.Resource != b.Resource {
+				colorBucketPrint(" " + GlyphHDotted)
+				_, _ = color.New(color.FgWhite).Println("\t\t\uf121 " + finding.Resource)
+			}
+			if finding.Status == audit.StatusNotApplicable || bpa {
+				continue
+			}
+
+			for _, check := range finding.Checks {
+				colorBucketPrint(" " + GlyphHDotted)
+				name := check.Name
+				if check.Location != "" {
+					name += " (" + check.Location + ")"
+				}
+				if check.Passed {
+					printPass("\t✔", " "+name)
+				} else {
+					printFail("\t✖", " "+name)
+				}
+			}
 		}
 
-		// CIS 2.1.5 Ensure that S3 Buckets are configured with 'Block public access'
-		/*
-		 * ✖ ✔ BlockPublicAcls (BPA)
-		 * ✖ ✔ BlockPublicPolicy (BPP)
-		 * ✖ ✔ IgnorePublicAcls (IPA)
-		 * ✖ ✔ RestrictPublicBuckets (RPB)
-		 */
-		colorBucketPrint(" " + GlyphHDotted)
-		_, _ = cCIS.Println("\tEnsure that S3 Buckets are configured with 'Block public access' [CIS 2.1.5]")
-		colorBucketPrint(" " + GlyphHDotted)
-		if b.BlockPublicAccess.BlockPublicAcls {
-			c := color.New(color.FgHiGreen).Add(color.Bold)
-			_, _ = c.Print("\t\t✔")
-			c = color.New(color.FgGreen)
-			_, _ = c.Println(" Block Public ACLs is enabled")
-		} else {
-			c := color.New(color.FgHiRed).Add(color.Bold)
-			_, _ = c.Print("\t\t✖")
-			c = color.New(color.FgRed)
-			_, _ = c.Println(" Block Public ACLs is disabled")
+		// bucket report END
+		colorBucketPrintln(" " + GlyphVDotted + "\n")
+
+		// color.Green("Ξ" + "⚠⚠" + "✗✗" + "☡☡" + "∆∆" + "≈≈")
+	}
+
+	if r.Framework != "" {
+		printFrameworkSummary(r.Framework, report)
+	}
+	return nil
+}
+
+// requirementLabel returns the framework's requirements evaluated by the finding's control, e.g. ' [FSBP S3.5]'.
+func (r *TextPrinter) requirementLabel(finding audit.Finding) string {
+	if r.Framework == "" || len(finding.Requirements) == 0 {
+		return ""
+	}
+	return " [" + r.Framework.Label() + " " + strings.Join(finding.Requirements, ", ") + "]"
+}
+
+// printFrameworkSummary prints the findings of all buckets grouped by the framework's requirements,
+// followed by the framework's coverage.
+func printFrameworkSummary(framework audit.Framework, reports []audit.BucketReport) {
+	cTitle := color.New(color.FgHiBlue).Add(color.Bold)
+	_, _ = cTitle.Println(" \uf0ae " + framework.Name())
+
+	width := 0
+	for _, requirement := range framework.Requirements() {
+		width = max(width, len(requirement.ID))
+	}
+	for _, result := range audit.FrameworkResults(framework, reports) {
+		id := fmt.Sprintf("%-*s", width, result.ID)
+		switch {
+		case !result.Evaluated:
+			c := color.New(color.FgWhite)
+			_, _ = c.Println("\t\t- " + id + "  " + result.Title + " (not evaluated)")
+			continue
+		case result.Failed != 0:
+			printFail("✖", " "+id+"  "+result.Title)
+		case result.Passed != 0:
+			printPass("✔", " "+id+"  "+result.Title)
+		default:
+			c := color.New(color.FgHiYellow)
+			_, _ = c.Println("\t\t- " + id + "  " + result.Title)
 		}
 
-		colorBucketPrint(" " + GlyphHDotted)
-		if b.BlockPublicAccess.BlockPublicPolicy {
-			c := color.New(color.FgHiGreen).Add(color.Bold)
-			_, _ = c.Print("\t\t✔")
-			c = color.New(color.FgGreen)
-			_, _ = c.Println(" Block Public Policy is enabled")
-		} else {
-			c := color.New(color.FgHiRed).Add(color.Bold)
-			_, _ = c.Print("\t\t✖")
-			c = color.New(color.FgRed)
-			_, _ = c.Println(" Block Public Policy is disabled")
+		var controls []string
+		for _, control := range result.Controls {
+			controls = append(controls, string(control))
 		}
+		summary := fmt.Sprintf("Findings: %d passed, %d failed, %d not applicable", result.Passed, result.Failed, result.NotApplicable)
+		_, _ = color.New(color.FgWhite).Println("\t\t  " + strings.Repeat(" ", width) + "  " + summary + " (" + strings.Join(controls, ", ") + ")")
+	}
+
+	evaluated, total := framework.Coverage()
+	_, _ = cTitle.Printf(" Coverage: %d of %d requirements evaluated (%d%%)\n\n", evaluated, total, evaluated*100/total)
+}
 
-		colorBucketPrint(" " + GlyphHDotted)
-		if b.BlockPublicAccess.IgnorePublicAcls {
-			c := color.New(color.FgHiGreen).Add(color.Bold)
-			_, _ = c.Print("\t\t✔")
-			c = color.New(color.FgGreen)
-			_, _ = c.Println(" Ignore Public ACLs is enabled")
-		} else {
-			c := color.New(color.FgHiRed).Add(color.Bold)
-			_, _ = c.Print("\t\t✖")
-			c = color.New(color.FgRed)
-			_, _ = c.Println(" Ignore Public ACLs is disabled")
+func formatTags(tags map[string]string) string {
+	var pairs []string
+	for _, key := range sortedKeys(tags) {
+		pairs = append(pairs, key+"="+tags[key])
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// controlTitle returns the title of the benchmark item the finding refers to, or else of the control.
+func controlTitle(control audit.Control, finding audit.Finding) string {
+	if item := finding.Benchmark; item != nil {
+		return item.Title + " [CIS " + item.Benchmark.Version() + " " + item.Item + "]"
+	}
+	if audit.IsCustomControl(control.ID) {
+		return control.Title + " (" + string(control.ID) + ")"
+	}
+	return control.Title + " (non-CIS)"
+}
+
+// leadingControls are printed first and in this order, as before the other controls were added.
+var leadingControls = []audit.ControlID{
+	audit.ControlEncryption, audit.ControlDenyHTTP, audit.ControlVersioning, audit.ControlBlockPublicAccess,
+}
+
+// textControls returns the controls of the bucket's findings in the order they are printed; MFA delete is
+// only reported in CSV and JSON.
+func textControls(b audit.BucketReport) []audit.ControlID {
+	var ids []audit.ControlID
+	for _, id := range leadingControls {
+		if _, ok := b.Finding(id); ok {
+			ids = append(ids, id)
 		}
+	}
+	for _, id := range audit.ReportControls(b) {
+		if !slices.Contains(leadingControls, id) && id != audit.ControlMFADelete {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
 
-		colorBucketPrint(" " + GlyphHDotted)
-		if b.BlockPublicAccess.RestrictPublicBuckets {
-			c := color.New(color.FgHiGreen).Add(color.Bold)
-			_, _ = c.Print("\t\t✔")
-			c = color.New(color.FgGreen)
-			_, _ = c.Println(" Restrict Public Access is enabled")
-		} else {
-			c := color.New(color.FgHiRed).Add(color.Bold)
-			_, _ = c.Print("\t\t✖")
-			c = color.New(color.FgRed)
-			_, _ = c.Println(" Restrict Public Access is disabled")
+// blockPublicAccessLabels are the printed names of the 'Block public access' settings.
+var blockPublicAccessLabels = map[string]string{"Restrict Public Buckets": "Restrict Public Access"}
+
+// printBlockPublicAccess prints each 'Block public access' setting as enabled or disabled, the severity
+// of the finding following the disabled ones.
+func printBlockPublicAccess(finding audit.Finding, printPrefix func()) {
+	for i, check := range finding.Checks {
+		if i != 0 {
+			printPrefix()
+		}
+		name := check.Name
+		if label, ok := blockPublicAccessLabels[name]; ok {
+			name = label
+		}
+		location := ""
+		if check.Location != "" {
+			location = " (" + check.Location + ")"
+		}
+		switch {
+		case check.Passed:
+			printPass("✔", " "+name+" is enabled"+location)
+		case finding.Suppressed():
+			c := color.New(color.FgHiMagenta)
+			_, _ = c.Print("\t\t\uf070")
+			_, _ = c.Println(" " + name + " is disabled" + location + " [" + string(finding.Severity) + ", SUPPRESSED]")
+		default:
+			printFail("✖", " "+name+" is disabled"+location+" ["+string(finding.Severity)+"]")
 		}
+	}
+}
 
-		// bucket report END
-		colorBucketPrintln(" " + GlyphVDotted + "\n")
+type statusGlyphs struct {
+	pass string
+	fail string
+}
 
-		// color.Green("Ξ" + "⚠⚠" + "✗✗" + "☡☡" + "∆∆" + "≈≈")
+func controlGlyphs(id audit.ControlID) statusGlyphs {
+	switch id {
+	case audit.ControlEncryption:
+		return statusGlyphs{"󰞚 ", "󰉀"}
+	case audit.ControlDenyHTTP:
+		return statusGlyphs{"\uf023 ", "\uf09c"}
+	case audit.ControlVersioning:
+		return statusGlyphs{"\uf454 ", "󰉀"}
+	default:
+		return statusGlyphs{"✔", "✖"}
 	}
-	return nil
+}
+
+func printPass(glyph string, message string) {
+	c := color.New(color.FgHiGreen).Add(color.Bold)
+	_, _ = c.Print("\t\t" + glyph)
+	c = color.New(color.FgGreen)
+	_, _ = c.Println(message)
+}
+
+func printFail(glyph string, message string) {
+	c := color.New(color.FgHiRed).Add(color.Bold)
+	_, _ = c.Print("\t\t" + glyph)
+	c = color.New(color.FgRed)
+	_, _ = c.Println(message)
 }
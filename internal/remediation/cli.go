@@ -0,0 +1,59 @@
+package remediation
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// cliCommands maps the S3 API operations of actions to the AWS CLI command and the option taking the document.
+var cliCommands = map[string][2]string{
+	"PutBucketEncryption":  {"put-bucket-encryption", "--server-side-encryption-configuration"},
+	"PutPublicAccessBlock": {"put-public-access-block", "--public-access-block-configuration"},
+	"PutBucketPolicy":      {"put-bucket-policy", "--policy"},
+	"PutBucketVersioning":  {"put-bucket-versioning", "--versioning-configuration"},
+}
+
+// CLIEmitter writes the actions as AWS CLI commands.
+type CLIEmitter struct{}
+
+func (e *CLIEmitter) Emit(plans []Plan, w io.Writer) error {
+	var b strings.Builder
+	writeSkipped(plans, &b)
+	if !hasActions(plans) {
+		b.WriteString(noActionsComment)
+	}
+
+	for _, plan := range plans {
+		for _, action := range plan.Actions {
+			command, ok := cliCommands[action.Operation]
+			if !ok {
+				return fmt.Errorf("unsupported operation %s", action.Operation)
+			}
+			if b.Len() != 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "# %s: %s\n", plan.Bucket, action.Description)
+			if input, ok := action.input.(policyStatement); ok {
+				writeStatement(&b, input.statement)
+				continue
+			}
+			fmt.Fprintf(&b, "aws s3api %s --bucket %s", command[0], shellQuote(plan.Bucket))
+			if plan.Region != "" {
+				fmt.Fprintf(&b, " --region %s", plan.Region)
+			}
+			if plan.AccountID != "" {
+				fmt.Fprintf(&b, " --expected-bucket-owner %s", plan.AccountID)
+			}
+			fmt.Fprintf(&b, " \\\n  %s %s\n", command[1], shellQuote(compact(action.Document)))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// shellQuote quotes s as a single POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
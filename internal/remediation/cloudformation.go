@@ -0,0 +1,191 @@
+package remediation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/rollwagen/s3-cisbench/internal/cloudformation"
+	"gopkg.in/yaml.v3"
+)
+
+var nonAlphanumericChars = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+type cfnResource struct {
+	Type           string         `yaml:"Type,omitempty"`
+	DeletionPolicy string         `yaml:"DeletionPolicy,omitempty"`
+	Properties     map[string]any `yaml:"Properties"`
+}
+
+type cfnTemplate struct {
+	AWSTemplateFormatVersion string                 `yaml:"AWSTemplateFormatVersion"`
+	Description              string                 `yaml:"Description"`
+	Resources                map[string]cfnResource `yaml:"Resources"`
+}
+
+// cfnResources holds the remediated properties of the resources of an audited template by logical ID.
+type cfnResources struct {
+	Resources map[string]cfnResource `yaml:"Resources"`
+}
+
+// CloudFormationEmitter writes the actions as CloudFormation resources. Buckets audited from a template
+// are remediated by properties keyed by the template's logical IDs, to be merged into the template; others
+// by bucket resources holding only the remediated properties, for importing the bucket into a stack.
+type CloudFormationEmitter struct{}
+
+func (e *CloudFormationEmitter) Emit(plans []Plan, w io.Writer) error {
+	var b strings.Builder
+	writeSkipped(plans, &b)
+	if !hasActions(plans) {
+		b.WriteString(noActionsComment)
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	template := cfnTemplate{
+		AWSTemplateFormatVersion: "2010-09-09",
+		Description:              "Remediation of failed S3 bucket controls",
+		Resources:                map[string]cfnResource{},
+	}
+	merged := map[string]cfnResources{} // by template file
+	var files []string
+	for _, plan := range plans {
+		if len(plan.Actions) == 0 {
+			continue
+		}
+
+		bucketID, file, fromTemplate := cloudformation.ParseLocation(plan.Resource)
+		policyID := bucketID + "Policy"
+		resources := template.Resources
+		bucket := cfnResource{Properties: map[string]any{}}
+		if fromTemplate {
+			if _, ok := merged[file]; !ok {
+				merged[file] = cfnResources{Resources: map[string]cfnResource{}}
+				files = append(files, file)
+			}
+			resources = merged[file].Resources
+		} else {
+			logicalID := cfnLogicalID(plan.Bucket)
+			for i := 2; template.Resources[logicalID+"Bucket"].Type != "" || template.Resources[logicalID+"BucketPolicy"].Type != ""; i++ {
+				logicalID = fmt.Sprintf("%s%d", cfnLogicalID(plan.Bucket), i)
+			}
+			bucketID, policyID = logicalID+"Bucket", logicalID+"BucketPolicy"
+			bucket = cfnResource{Type: "AWS::S3::Bucket", DeletionPolicy: "Retain", Properties: map[string]any{"BucketName": plan.Bucket}}
+		}
+		properties := len(bucket.Properties)
+
+		for _, action := range plan.Actions {
+			b.WriteString(fmt.Sprintf("# %s: %s\n", plan.Bucket, action.Description))
+			switch input := action.input.(type) {
+			case *s3.PutBucketEncryptionInput:
+				var rules []any
+				for _, rule := range input.ServerSideEncryptionConfiguration.Rules {
+					byDefault := map[string]any{"SSEAlgorithm": string(rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm)}
+					if keyID := rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID; keyID != nil {
+						byDefault["KMSMasterKeyID"] = *keyID
+					}
+					r := map[string]any{"ServerSideEncryptionByDefault": byDefault}
+					if rule.BucketKeyEnabled != nil {
+						r["BucketKeyEnabled"] = *rule.BucketKeyEnabled
+					}
+					rules = append(rules, r)
+				}
+				bucket.Properties["BucketEncryption"] = map[string]any{"ServerSideEncryptionConfiguration": rules}
+
+			case *s3.PutPublicAccessBlockInput:
+				configuration := input.PublicAccessBlockConfiguration
+				bucket.Properties["PublicAccessBlockConfiguration"] = map[string]bool{
+					"BlockPublicAcls":       *configuration.BlockPublicAcls,
+					"BlockPublicPolicy":     *configuration.BlockPublicPolicy,
+					"IgnorePublicAcls":      *configuration.IgnorePublicAcls,
+					"RestrictPublicBuckets": *configuration.RestrictPublicBuckets,
+				}
+
+			case *s3.PutBucketPolicyInput:
+				var document map[string]any
+				if err := json.Unmarshal([]byte(*input.Policy), &document); err != nil {
+					return fmt.Errorf("could not parse bucket policy of %s: %v", plan.Bucket, err)
+				}
+				resources[policyID] = cfnResource{
+					Type:       "AWS::S3::BucketPolicy",
+					Properties: map[string]any{"Bucket": plan.Bucket, "PolicyDocument": document},
+				}
+
+			case policyStatement:
+				var statement map[string]any
+				if err := json.Unmarshal([]byte(input.statement), &statement); err != nil {
+					return err
+				}
+				id, _, ok := cloudformation.ParseLocation(action.Resource)
+				switch {
+				case !fromTemplate:
+					writeStatement(&b, input.statement)
+				case ok:
+					// the statement is added to the template's bucket policy
+					resources[id] = cfnResource{Properties: map[string]any{"PolicyDocument": map[string]any{"Statement": []any{statement}}}}
+				default:
+					resources[policyID] = cfnResource{
+						Type: "AWS::S3::BucketPolicy",
+						Properties: map[string]any{
+							"Bucket":         map[string]string{"Ref": bucketID},
+							"PolicyDocument": map[string]any{"Version": policyVersion, "Statement": []any{statement}},
+						},
+					}
+				}
+
+			case *s3.PutBucketVersioningInput:
+				bucket.Properties["VersioningConfiguration"] = map[string]string{"Status": string(input.VersioningConfiguration.Status)}
+
+			default:
+				return fmt.Errorf("unsupported operation %s", action.Operation)
+			}
+		}
+		if len(bucket.Properties) > properties {
+			resources[bucketID] = bucket
+		}
+	}
+
+	var documents int
+	encode := func(comment string, v any) error {
+		if documents++; documents > 1 {
+			b.WriteString("---\n")
+		}
+		b.WriteString(comment)
+		encoder := yaml.NewEncoder(&b)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+	if len(template.Resources) != 0 {
+		comment := "# Bucket resources hold only the remediated properties; merge them into the template defining\n" +
+			"# the bucket, or complete them before importing the bucket into a stack.\n"
+		if err := encode(comment, template); err != nil {
+			return err
+		}
+	}
+	for _, file := range files {
+		comment := "# Merge into the resources of " + file + "; policy statements are added to the template's policy document.\n"
+		if err := encode(comment, merged[file]); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// cfnLogicalID converts a bucket name into a CloudFormation logical ID prefix, e.g. 'my-bucket.logs' into 'MyBucketLogs'.
+func cfnLogicalID(bucketName string) string {
+	var id strings.Builder
+	for _, part := range nonAlphanumericChars.Split(bucketName, -1) {
+		if part != "" {
+			id.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return id.String()
+}
@@ -0,0 +1,74 @@
+package remediation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Emitter is an interface that knows how to write remediation plans as artifacts for a change process,
+// e.g. infrastructure as code to review instead of direct API writes.
+type Emitter interface {
+	// Emit receives remediation plans, converts their actions and writes them to a writer.
+	Emit([]Plan, io.Writer) error
+}
+
+// EmitFormats returns the formats supported by NewEmitter.
+func EmitFormats() []string {
+	return []string{"terraform", "cloudformation", "cli"}
+}
+
+// NewEmitter returns the emitter for the given format.
+func NewEmitter(format string) (Emitter, error) {
+	switch format {
+	case "terraform":
+		return &TerraformEmitter{}, nil
+	case "cloudformation":
+		return &CloudFormationEmitter{}, nil
+	case "cli":
+		return &CLIEmitter{}, nil
+	}
+	return nil, fmt.Errorf("invalid emit format '%s': expected one of %s", format, strings.Join(EmitFormats(), ", "))
+}
+
+// noActionsComment is written if no plan has an action; failed controls that cannot be remediated are
+// listed as skipped.
+const noActionsComment = "# No remediable failed controls\n"
+
+// writeSkipped writes the controls of the plans that cannot be remediated as comments.
+func writeSkipped(plans []Plan, w io.Writer) {
+	for _, plan := range plans {
+		for _, skipped := range plan.Skipped {
+			_, _ = fmt.Fprintf(w, "# %s: skipped %s\n", plan.Bucket, skipped)
+		}
+	}
+}
+
+// writeStatement writes the statement to add to a bucket policy that is not replaced as comments.
+func writeStatement(w io.Writer, statement string) {
+	_, _ = io.WriteString(w, "# Add to the statements of the bucket policy:\n")
+	for _, line := range strings.Split(statement, "\n") {
+		_, _ = fmt.Fprintf(w, "#   %s\n", line)
+	}
+}
+
+// hasActions reports whether any plan has an action.
+func hasActions(plans []Plan) bool {
+	for _, plan := range plans {
+		if len(plan.Actions) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// compact returns the JSON document without insignificant whitespace.
+func compact(document string) string {
+	var b bytes.Buffer
+	if err := json.Compact(&b, []byte(document)); err != nil {
+		return document
+	}
+	return b.String()
+}
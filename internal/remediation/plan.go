@@ -0,0 +1,206 @@
+// Package remediation computes and applies the S3 API changes fixing failed controls of a bucket.
+package remediation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/rollwagen/s3-cisbench/internal/audit"
+	"github.com/rollwagen/s3-cisbench/internal/aws"
+	log "github.com/sirupsen/logrus"
+)
+
+// Controls returns the controls that can be remediated.
+func Controls() []audit.ControlID {
+	return []audit.ControlID{audit.ControlEncryption, audit.ControlBlockPublicAccess, audit.ControlDenyHTTP, audit.ControlVersioning}
+}
+
+// Options configure which controls are remediated and how.
+type Options struct {
+	Controls []audit.ControlID // controls to remediate; all of Controls() if empty
+	KMSKeyID string            // if set, default encryption uses this KMS key instead of SSE-S3
+	// Live is set if the reports were read from AWS by this run. Otherwise, e.g. for templates or a state file,
+	// the bucket policy is not replaced; only the statement to add is planned.
+	Live bool
+}
+
+// Action is a single API change remediating a failed control.
+type Action struct {
+	Control     audit.ControlID `json:"control"`
+	Operation   string          `json:"operation"` // S3 API operation, e.g. PutBucketEncryption
+	Description string          `json:"description"`
+	Document    string          `json:"document"`           // request payload in AWS CLI JSON format, e.g. the complete bucket policy, or the statement to add
+	Resource    string          `json:"resource,omitempty"` // resource defining the configuration in pre-deployment audits
+	input       any
+}
+
+// Plan holds the actions remediating the failed controls of a bucket.
+type Plan struct {
+	Bucket    string   `json:"bucket"`
+	AccountID string   `json:"accountId"`
+	Region    string   `json:"region"`
+	Resource  string   `json:"resource,omitempty"` // resource defining the bucket in pre-deployment audits
+	Actions   []Action `json:"actions"`
+	Skipped   []string `json:"skipped,omitempty"` // failed controls that cannot be remediated, with the reason why
+}
+
+// policyStatement is the input of an action adding a statement to a bucket policy that was not read from
+// AWS; it is merged manually instead of replacing the policy.
+type policyStatement struct {
+	statement string
+}
+
+func document(v any) string {
+	b, _ := json.MarshalIndent(v, "", "  ")
+	return string(b)
+}
+
+// NewPlan computes the actions remediating the failed controls of an evaluated bucket report.
+func NewPlan(report audit.BucketReport, options Options) (Plan, error) {
+	plan := Plan{Bucket: report.Name, AccountID: report.AccountID, Region: report.Region, Resource: report.Resource}
+	owner := aws.ExpectedBucketOwner(report.AccountID)
+
+	for _, control := range Controls() {
+		if len(options.Controls) != 0 && !slices.Contains(options.Controls, control) {
+			continue
+		}
+		// e.g. a policy that could not be read must not be replaced by a new one
+		if _, unavailable := report.Unavailable[control]; unavailable {
+			continue
+		}
+		finding, ok := report.Finding(control)
+		if !ok || finding.Status != audit.StatusFail {
+			continue
+		}
+		if finding.Suppressed() {
+			plan.Skipped = append(plan.Skipped, fmt.Sprintf("%s: suppressed until %s: %s",
+				control, finding.Suppression.Expires, finding.Suppression.Justification))
+			continue
+		}
+
+		switch control {
+		case audit.ControlEncryption:
+			byDefault := &types.ServerSideEncryptionByDefault{SSEAlgorithm: types.ServerSideEncryptionAes256}
+			description := "Enable default encryption with SSE-S3"
+			if options.KMSKeyID != "" {
+				byDefault = &types.ServerSideEncryptionByDefault{SSEAlgorithm: types.ServerSideEncryptionAwsKms, KMSMasterKeyID: &options.KMSKeyID}
+				description = "Enable default encryption with KMS key " + options.KMSKeyID
+			} else if report.ServerSideEncryptionEnabled {
+				// encryption is enabled, but the profile requires KMS encryption
+				plan.Skipped = append(plan.Skipped, fmt.Sprintf("%s: KMS encryption required, but no KMS key given", control))
+				continue
+			}
+			bucketKey := true
+			configuration := &types.ServerSideEncryptionConfiguration{
+				Rules: []types.ServerSideEncryptionRule{{ApplyServerSideEncryptionByDefault: byDefault, BucketKeyEnabled: &bucketKey}},
+			}
+			byDefaultDocument := map[string]string{"SSEAlgorithm": string(byDefault.SSEAlgorithm)}
+			if options.KMSKeyID != "" {
+				byDefaultDocument["KMSMasterKeyID"] = options.KMSKeyID
+			}
+			plan.Actions = append(plan.Actions, Action{
+				Control: control, Operation: "PutBucketEncryption", Description: description,
+				Document: document(map[string]any{
+					"Rules": []any{map[string]any{"ApplyServerSideEncryptionByDefault": byDefaultDocument, "BucketKeyEnabled": bucketKey}},
+				}),
+				input: &s3.PutBucketEncryptionInput{Bucket: &plan.Bucket, ServerSideEncryptionConfiguration: configuration, ExpectedBucketOwner: owner},
+			})
+
+		case audit.ControlBlockPublicAccess:
+			enabled := true
+			configuration := &types.PublicAccessBlockConfiguration{
+				BlockPublicAcls: &enabled, BlockPublicPolicy: &enabled, IgnorePublicAcls: &enabled, RestrictPublicBuckets: &enabled,
+			}
+			plan.Actions = append(plan.Actions, Action{
+				Control: control, Operation: "PutPublicAccessBlock", Description: "Enable all 'Block public access' settings",
+				Document: document(map[string]bool{
+					"BlockPublicAcls": true, "BlockPublicPolicy": true, "IgnorePublicAcls": true, "RestrictPublicBuckets": true,
+				}),
+				input: &s3.PutPublicAccessBlockInput{Bucket: &plan.Bucket, PublicAccessBlockConfiguration: configuration, ExpectedBucketOwner: owner},
+			})
+
+		case audit.ControlDenyHTTP:
+			if !options.Live {
+				// the policy of e.g. a template or an earlier state would replace the bucket's current policy
+				statement, err := NewDenyHTTPStatement(report.Name, report.Policy)
+				if err != nil {
+					return plan, err
+				}
+				plan.Actions = append(plan.Actions, Action{
+					Control: control, Operation: "PutBucketPolicy", Description: "Add statement denying HTTP requests to the bucket policy",
+					Document: statement,
+					input:    policyStatement{statement: statement},
+				})
+				break
+			}
+			policy, err := MergeDenyHTTPStatement(report.Name, report.Policy)
+			if err != nil {
+				return plan, err
+			}
+			description := "Add statement denying HTTP requests to the bucket policy"
+			if report.Policy == "" {
+				description = "Create bucket policy denying HTTP requests"
+			}
+			plan.Actions = append(plan.Actions, Action{
+				Control: control, Operation: "PutBucketPolicy", Description: description,
+				Document: policy,
+				input:    &s3.PutBucketPolicyInput{Bucket: &plan.Bucket, Policy: &policy, ExpectedBucketOwner: owner},
+			})
+
+		case audit.ControlVersioning:
+			configuration := &types.VersioningConfiguration{Status: types.BucketVersioningStatusEnabled}
+			plan.Actions = append(plan.Actions, Action{
+				Control: control, Operation: "PutBucketVersioning", Description: "Enable versioning",
+				Document: document(map[string]string{"Status": string(configuration.Status)}),
+				input:    &s3.PutBucketVersioningInput{Bucket: &plan.Bucket, VersioningConfiguration: configuration, ExpectedBucketOwner: owner},
+			})
+		}
+	}
+	for i, action := range plan.Actions {
+		plan.Actions[i].Resource = report.Resources[action.Control]
+	}
+
+	if len(options.Controls) == 0 {
+		for _, finding := range report.Findings {
+			if finding.Status == audit.StatusFail && !finding.Suppressed() && !slices.Contains(Controls(), finding.Control) {
+				plan.Skipped = append(plan.Skipped, fmt.Sprintf("%s: cannot be remediated", finding.Control))
+			}
+		}
+	}
+	return plan, nil
+}
+
+// Apply applies the actions of the plan in order and stops at the first error.
+func (p Plan) Apply(ctx context.Context) error {
+	s3Client, err := aws.NewS3Client(p.Region)
+	if err != nil {
+		return err
+	}
+
+	for _, action := range p.Actions {
+		log.Debugf("Applying %s to bucket %s", action.Operation, p.Bucket)
+		switch input := action.input.(type) {
+		case *s3.PutBucketEncryptionInput:
+			_, err = s3Client.PutBucketEncryption(ctx, input)
+		case *s3.PutPublicAccessBlockInput:
+			_, err = s3Client.PutPublicAccessBlock(ctx, input)
+		case *s3.PutBucketPolicyInput:
+			_, err = s3Client.PutBucketPolicy(ctx, input)
+		case *s3.PutBucketVersioningInput:
+			_, err = s3Client.PutBucketVersioning(ctx, input)
+		case policyStatement:
+			err = errors.New("the statement must be added to the bucket policy manually")
+		default:
+			err = fmt.Errorf("unsupported operation %s", action.Operation)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", action.Operation, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package remediation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+const (
+	policyVersion = "2012-10-17"
+	denyHTTPSid   = "DenyInsecureTransport"
+)
+
+// denyHTTPStatement returns the statement denying all requests to the bucket not using HTTPS,
+// as expected by the deny HTTP control.
+func denyHTTPStatement(sid string, bucketName string) map[string]any {
+	bucketARN := "arn:aws:s3:::" + bucketName
+	return map[string]any{
+		"Sid":       sid,
+		"Effect":    "Deny",
+		"Principal": "*",
+		"Action":    "s3:*",
+		"Resource":  []string{bucketARN, bucketARN + "/*"},
+		"Condition": map[string]any{"Bool": map[string]string{"aws:SecureTransport": "false"}},
+	}
+}
+
+// parsePolicy returns the document of a bucket policy and its statements; an empty policy has none.
+func parsePolicy(bucketName string, policy string) (map[string]json.RawMessage, []json.RawMessage, error) {
+	document := map[string]json.RawMessage{}
+	var statements []json.RawMessage
+
+	if policy != "" {
+		if err := json.Unmarshal([]byte(policy), &document); err != nil {
+			return nil, nil, fmt.Errorf("could not parse bucket policy of %s: %v", bucketName, err)
+		}
+		if raw, ok := document["Statement"]; ok {
+			// a single statement object or a list of statements
+			if err := json.Unmarshal(raw, &statements); err != nil {
+				statements = []json.RawMessage{raw}
+			}
+		}
+	}
+	return document, statements, nil
+}
+
+// uniqueDenyHTTPSid returns the Sid of the statement denying HTTP requests, which must be unique within the policy.
+func uniqueDenyHTTPSid(statements []json.RawMessage) string {
+	sids := map[string]bool{}
+	for _, s := range statements {
+		var statement struct{ Sid string }
+		_ = json.Unmarshal(s, &statement)
+		sids[statement.Sid] = true
+	}
+	sid := denyHTTPSid
+	for i := 2; sids[sid]; i++ {
+		sid = denyHTTPSid + strconv.Itoa(i)
+	}
+	return sid
+}
+
+// NewDenyHTTPStatement returns the statement denying HTTP requests to add to a bucket policy, e.g. one that
+// was not read from AWS and must not replace the bucket's policy.
+func NewDenyHTTPStatement(bucketName string, policy string) (string, error) {
+	_, statements, err := parsePolicy(bucketName, policy)
+	if err != nil {
+		return "", err
+	}
+	statement, err := json.MarshalIndent(denyHTTPStatement(uniqueDenyHTTPSid(statements), bucketName), "", "  ")
+	return string(statement), err
+}
+
+// MergeDenyHTTPStatement adds a statement denying HTTP requests to an existing bucket policy, keeping all
+// existing statements; without an existing policy, a new policy with only this statement is returned.
+func MergeDenyHTTPStatement(bucketName string, policy string) (string, error) {
+	document, statements, err := parsePolicy(bucketName, policy)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := document["Version"]; !ok {
+		document["Version"], _ = json.Marshal(policyVersion)
+	}
+
+	statement, err := json.Marshal(denyHTTPStatement(uniqueDenyHTTPSid(statements), bucketName))
+	if err != nil {
+		return "", err
+	}
+	statements = append(statements, statement)
+	if document["Statement"], err = json.Marshal(statements); err != nil {
+		return "", err
+	}
+
+	merged, err := json.MarshalIndent(document, "", "  ")
+	return string(merged), err
+}
@@ -0,0 +1,109 @@
+package remediation
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+var nonIdentifierChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// TerraformEmitter writes the actions as Terraform resources of the AWS provider.
+type TerraformEmitter struct{}
+
+func (e *TerraformEmitter) Emit(plans []Plan, w io.Writer) error {
+	var b strings.Builder
+	writeSkipped(plans, &b)
+	if !hasActions(plans) {
+		b.WriteString(noActionsComment)
+	}
+
+	names := map[string]int{}
+	for _, plan := range plans {
+		if len(plan.Actions) == 0 {
+			continue
+		}
+		name := terraformName(plan.Bucket)
+		if names[name]++; names[name] > 1 {
+			name = fmt.Sprintf("%s_%d", name, names[name])
+		}
+
+		for _, action := range plan.Actions {
+			if b.Len() != 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "# %s: %s\n", plan.Bucket, action.Description)
+			switch input := action.input.(type) {
+			case *s3.PutBucketEncryptionInput:
+				fmt.Fprintf(&b, "resource \"aws_s3_bucket_server_side_encryption_configuration\" %q {\n", name)
+				fmt.Fprintf(&b, "  bucket = %q\n", plan.Bucket)
+				for _, rule := range input.ServerSideEncryptionConfiguration.Rules {
+					b.WriteString("\n  rule {\n    apply_server_side_encryption_by_default {\n")
+					byDefault := rule.ApplyServerSideEncryptionByDefault
+					fmt.Fprintf(&b, "      sse_algorithm = %q\n", byDefault.SSEAlgorithm)
+					if byDefault.KMSMasterKeyID != nil {
+						fmt.Fprintf(&b, "      kms_master_key_id = %q\n", *byDefault.KMSMasterKeyID)
+					}
+					b.WriteString("    }\n")
+					if rule.BucketKeyEnabled != nil {
+						fmt.Fprintf(&b, "    bucket_key_enabled = %t\n", *rule.BucketKeyEnabled)
+					}
+					b.WriteString("  }\n")
+				}
+				b.WriteString("}\n")
+
+			case *s3.PutPublicAccessBlockInput:
+				configuration := input.PublicAccessBlockConfiguration
+				fmt.Fprintf(&b, "resource \"aws_s3_bucket_public_access_block\" %q {\n", name)
+				fmt.Fprintf(&b, "  bucket = %q\n\n", plan.Bucket)
+				fmt.Fprintf(&b, "  block_public_acls       = %t\n", *configuration.BlockPublicAcls)
+				fmt.Fprintf(&b, "  block_public_policy     = %t\n", *configuration.BlockPublicPolicy)
+				fmt.Fprintf(&b, "  ignore_public_acls      = %t\n", *configuration.IgnorePublicAcls)
+				fmt.Fprintf(&b, "  restrict_public_buckets = %t\n", *configuration.RestrictPublicBuckets)
+				b.WriteString("}\n")
+
+			case *s3.PutBucketPolicyInput:
+				fmt.Fprintf(&b, "resource \"aws_s3_bucket_policy\" %q {\n", name)
+				fmt.Fprintf(&b, "  bucket = %q\n", plan.Bucket)
+				b.WriteString("  policy = <<-EOT\n")
+				for _, line := range strings.Split(escapeTemplate(*input.Policy), "\n") {
+					b.WriteString("    " + line + "\n")
+				}
+				b.WriteString("  EOT\n}\n")
+
+			case policyStatement:
+				writeStatement(&b, input.statement)
+
+			case *s3.PutBucketVersioningInput:
+				fmt.Fprintf(&b, "resource \"aws_s3_bucket_versioning\" %q {\n", name)
+				fmt.Fprintf(&b, "  bucket = %q\n\n", plan.Bucket)
+				b.WriteString("  versioning_configuration {\n")
+				fmt.Fprintf(&b, "    status = %q\n", input.VersioningConfiguration.Status)
+				b.WriteString("  }\n}\n")
+
+			default:
+				return fmt.Errorf("unsupported operation %s", action.Operation)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// terraformName converts a bucket name into a Terraform resource name, e.g. 'my.bucket' into 'my_bucket'.
+func terraformName(bucketName string) string {
+	name := nonIdentifierChars.ReplaceAllString(bucketName, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "bucket_" + name
+	}
+	return name
+}
+
+// escapeTemplate escapes template sequences, e.g. policy variables like '${aws:username}', in a heredoc string.
+func escapeTemplate(s string) string {
+	return strings.NewReplacer("${", "$${", "%{", "%%{").Replace(s)
+}
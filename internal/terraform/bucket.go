@@ -0,0 +1,400 @@
+package terraform
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rollwagen/s3-cisbench/internal/audit"
+)
+
+const (
+	// S3 applies SSE-S3 to all buckets without a default encryption configuration
+	defaultEncryptionAlgorithm = "AES256"
+	daysPerYear                = 365
+
+	unknownReason = "Not known until apply"
+	bpaReason     = "Public access block not managed by Terraform (new buckets block all public access by default)"
+)
+
+// bucket collects the configuration of an aws_s3_bucket and the resources applying to it.
+type bucket struct {
+	resource      resource
+	state         audit.BucketState
+	encryptionSet bool
+	bpaSet        bool
+}
+
+// bucketResources maps the resources configuring a bucket to the function applying their values.
+var bucketResources = map[string]func(b *bucket, r resource, unknown map[string]any){
+	"aws_s3_bucket_versioning":                           applyVersioning,
+	"aws_s3_bucket_server_side_encryption_configuration": applyEncryption,
+	"aws_s3_bucket_public_access_block":                  applyPublicAccessBlock,
+	"aws_s3_bucket_policy":                               applyPolicy,
+	"aws_s3_bucket_object_lock_configuration":            applyObjectLock,
+	"aws_s3_bucket_lifecycle_configuration":              applyLifecycle,
+	"aws_s3_bucket_replication_configuration":            applyReplication,
+	"aws_s3_bucket_website_configuration":                applyWebsite,
+	"aws_s3_bucket_cors_configuration":                   applyCORS,
+	"aws_s3_bucket_notification":                         applyNotification,
+}
+
+// Attribute values are JSON decoded; nested blocks are lists of objects.
+
+func str(m map[string]any, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+func optional(m map[string]any, key string) *string {
+	if s := str(m, key); s != "" {
+		return &s
+	}
+	return nil
+}
+
+func boolean(m map[string]any, key string) bool {
+	b, _ := m[key].(bool)
+	return b
+}
+
+func number(m map[string]any, key string) (int32, bool) {
+	f, ok := m[key].(float64)
+	return int32(f), ok
+}
+
+func strs(m map[string]any, key string) []string {
+	list, _ := m[key].([]any)
+	var values []string
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+func blocks(m map[string]any, key string) []map[string]any {
+	list, _ := m[key].([]any)
+	var values []map[string]any
+	for _, item := range list {
+		if block, ok := item.(map[string]any); ok {
+			values = append(values, block)
+		}
+	}
+	return values
+}
+
+func block(m map[string]any, key string) map[string]any {
+	if b := blocks(m, key); len(b) != 0 {
+		return b[0]
+	}
+	return nil
+}
+
+func isUnknown(unknown map[string]any, key string) bool {
+	u, _ := unknown[key].(bool)
+	return u
+}
+
+func newBucket(r resource, unknown map[string]any, accountID string, region string) *bucket {
+	b := &bucket{resource: r}
+	v := r.Values
+
+	b.state.Name = str(v, "bucket")
+	if b.state.Name == "" { // bucket_prefix or computed name
+		b.state.Name = r.Address
+	}
+	b.state.Resource = r.Address
+	b.state.AccountID = accountID
+	b.state.Region = region
+	if s := str(v, "region"); s != "" {
+		b.state.Region = s
+	}
+
+	tags, ok := v["tags_all"].(map[string]any)
+	if !ok {
+		tags, _ = v["tags"].(map[string]any)
+	}
+	if len(tags) != 0 {
+		b.state.Tags = map[string]string{}
+		for key, value := range tags {
+			b.state.Tags[key], _ = value.(string)
+		}
+	}
+
+	b.state.ObjectLock.Enabled = boolean(v, "object_lock_enabled")
+	b.applyInline(v, unknown)
+	return b
+}
+
+// applyInline applies the deprecated inline configuration blocks of aws_s3_bucket; resources like
+// aws_s3_bucket_versioning take precedence as they are applied afterwards.
+func (b *bucket) applyInline(v map[string]any, unknown map[string]any) {
+	state := &b.state
+
+	if versioning := block(v, "versioning"); versioning != nil {
+		state.VersioningEnabled = boolean(versioning, "enabled")
+		state.MFADelete = boolean(versioning, "mfa_delete")
+	}
+
+	if sse := block(v, "server_side_encryption_configuration"); sse != nil {
+		for _, rule := range blocks(sse, "rule") {
+			b.setEncryption(block(rule, "apply_server_side_encryption_by_default"))
+		}
+	}
+
+	state.Policy = str(v, "policy")
+	if isUnknown(unknown, "policy") {
+		state.MarkUnavailable(unknownReason, audit.PolicyControls()...)
+	}
+
+	if lock := block(v, "object_lock_configuration"); lock != nil {
+		state.ObjectLock.Enabled = state.ObjectLock.Enabled || str(lock, "object_lock_enabled") == "Enabled"
+		b.setDefaultRetention(block(block(lock, "rule"), "default_retention"))
+	}
+
+	for _, rule := range blocks(v, "lifecycle_rule") {
+		ruleReport := audit.LifecycleRuleReport{ID: str(rule, "id"), Enabled: boolean(rule, "enabled")}
+		abort, hasAbort := number(rule, "abort_incomplete_multipart_upload_days")
+		hasAbort = hasAbort && abort > 0
+		ruleReport.AbortIncompleteMultipartUploadDays = abort
+		noncurrent := block(rule, "noncurrent_version_expiration")
+		if noncurrent != nil {
+			ruleReport.NoncurrentVersionExpirationDays, _ = number(noncurrent, "days")
+		}
+		ruleReport.Transitions = transitions(blocks(rule, "transition"), "days")
+		ruleReport.NoncurrentVersionTransitions = transitions(blocks(rule, "noncurrent_version_transition"), "days")
+		b.addLifecycleRule(ruleReport, noncurrent != nil, hasAbort)
+	}
+
+	if replication := block(v, "replication_configuration"); replication != nil {
+		state.Replication.Role = str(replication, "role")
+		for _, rule := range blocks(replication, "rules") {
+			ruleReport := audit.ReplicationRuleReport{
+				ID:                      str(rule, "id"),
+				Enabled:                 str(rule, "status") == "Enabled",
+				DeleteMarkerReplication: str(rule, "delete_marker_replication_status") == "Enabled",
+			}
+			if d := block(rule, "destination"); d != nil {
+				ruleReport.DestinationBucket = strings.TrimPrefix(str(d, "bucket"), "arn:aws:s3:::")
+				ruleReport.DestinationAccount = str(d, "account_id")
+				ruleReport.ReplicaKMSKeyID = str(d, "replica_kms_key_id")
+			}
+			state.Replication.Rules = append(state.Replication.Rules, ruleReport)
+		}
+	}
+
+	if website := block(v, "website"); website != nil {
+		w := &state.Website
+		w.IndexDocument = str(website, "index_document")
+		w.ErrorDocument = str(website, "error_document")
+		w.RedirectAllTo = str(website, "redirect_all_requests_to")
+		w.Enabled = w.IndexDocument != "" || w.RedirectAllTo != ""
+		if w.Enabled {
+			w.Endpoint = audit.WebsiteEndpoint(state.Name, state.Region)
+		}
+	}
+
+	for _, rule := range blocks(v, "cors_rule") {
+		state.CORS.Rules = append(state.CORS.Rules,
+			audit.NewCORSRuleReport(optional(rule, "id"), strs(rule, "allowed_origins"), strs(rule, "allowed_methods")))
+	}
+}
+
+func (b *bucket) setEncryption(byDefault map[string]any) {
+	if byDefault == nil {
+		return
+	}
+	b.encryptionSet = true
+	b.state.ServerSideEncryptionEnabled = true
+	b.state.EncryptionAlgorithm = str(byDefault, "sse_algorithm")
+	b.state.CustomerManagedKey = b.state.EncryptionAlgorithm == "aws:kms" && str(byDefault, "kms_master_key_id") != ""
+}
+
+func (b *bucket) setDefaultRetention(retention map[string]any) {
+	if retention == nil {
+		return
+	}
+	b.state.ObjectLock.Mode = str(retention, "mode")
+	if days, ok := number(retention, "days"); ok && days > 0 {
+		b.state.ObjectLock.RetentionDays = days
+	}
+	if years, ok := number(retention, "years"); ok && years > 0 {
+		b.state.ObjectLock.RetentionDays = years * daysPerYear
+	}
+}
+
+func (b *bucket) addLifecycleRule(rule audit.LifecycleRuleReport, noncurrentExpiration bool, abortIncompleteUpload bool) {
+	lifecycle := &b.state.Lifecycle
+	lifecycle.Rules = append(lifecycle.Rules, rule)
+	if rule.Enabled && noncurrentExpiration {
+		lifecycle.NoncurrentVersionExpiration = true
+	}
+	if rule.Enabled && abortIncompleteUpload {
+		lifecycle.AbortIncompleteMultipartUpload = true
+	}
+}
+
+func transitions(list []map[string]any, daysKey string) []string {
+	var descriptions []string
+	for _, t := range list {
+		if days, ok := number(t, daysKey); ok {
+			descriptions = append(descriptions, fmt.Sprintf("%s after %d days", str(t, "storage_class"), days))
+		} else if date := str(t, "date"); date != "" {
+			descriptions = append(descriptions, fmt.Sprintf("%s on %s", str(t, "storage_class"), date))
+		}
+	}
+	return descriptions
+}
+
+func applyVersioning(b *bucket, r resource, _ map[string]any) {
+	versioning := block(r.Values, "versioning_configuration")
+	b.state.VersioningEnabled = str(versioning, "status") == "Enabled"
+	b.state.MFADelete = str(versioning, "mfa_delete") == "Enabled"
+	b.state.SetResource(r.Address, audit.ControlVersioning, audit.ControlMFADelete)
+}
+
+func applyEncryption(b *bucket, r resource, _ map[string]any) {
+	for _, rule := range blocks(r.Values, "rule") {
+		b.setEncryption(block(rule, "apply_server_side_encryption_by_default"))
+	}
+	b.state.SetResource(r.Address, audit.ControlEncryption)
+}
+
+func applyPublicAccessBlock(b *bucket, r resource, _ map[string]any) {
+	b.bpaSet = true
+	bpa := &b.state.BlockPublicAccess
+	bpa.BlockPublicAcls = boolean(r.Values, "block_public_acls")
+	bpa.BlockPublicPolicy = boolean(r.Values, "block_public_policy")
+	bpa.IgnorePublicAcls = boolean(r.Values, "ignore_public_acls")
+	bpa.RestrictPublicBuckets = boolean(r.Values, "restrict_public_buckets")
+	b.state.SetResource(r.Address, audit.ControlBlockPublicAccess)
+}
+
+func applyPolicy(b *bucket, r resource, unknown map[string]any) {
+	b.state.SetResource(r.Address, audit.PolicyControls()...)
+	b.state.Policy = str(r.Values, "policy")
+	if b.state.Policy == "" || isUnknown(unknown, "policy") {
+		b.state.MarkUnavailable(unknownReason, audit.PolicyControls()...)
+		return
+	}
+	for _, id := range audit.PolicyControls() { // replaces a computed inline policy
+		delete(b.state.Unavailable, id)
+	}
+}
+
+func applyObjectLock(b *bucket, r resource, _ map[string]any) {
+	// the configuration resource requires Object Lock to be enabled on the bucket
+	b.state.ObjectLock.Enabled = b.state.ObjectLock.Enabled || str(r.Values, "object_lock_enabled") == "Enabled"
+	b.setDefaultRetention(block(block(r.Values, "rule"), "default_retention"))
+	b.state.SetResource(r.Address, audit.ControlObjectLock)
+}
+
+func applyLifecycle(b *bucket, r resource, _ map[string]any) {
+	b.state.Lifecycle = audit.LifecycleReport{}
+	for _, rule := range blocks(r.Values, "rule") {
+		ruleReport := audit.LifecycleRuleReport{ID: str(rule, "id"), Enabled: str(rule, "status") == "Enabled"}
+		abort := block(rule, "abort_incomplete_multipart_upload")
+		if abort != nil {
+			ruleReport.AbortIncompleteMultipartUploadDays, _ = number(abort, "days_after_initiation")
+		}
+		noncurrent := block(rule, "noncurrent_version_expiration")
+		if noncurrent != nil {
+			ruleReport.NoncurrentVersionExpirationDays, _ = number(noncurrent, "noncurrent_days")
+		}
+		ruleReport.Transitions = transitions(blocks(rule, "transition"), "days")
+		ruleReport.NoncurrentVersionTransitions = transitions(blocks(rule, "noncurrent_version_transition"), "noncurrent_days")
+		b.addLifecycleRule(ruleReport, noncurrent != nil, abort != nil)
+	}
+	b.state.SetResource(r.Address, audit.ControlLifecycle)
+}
+
+func applyReplication(b *bucket, r resource, _ map[string]any) {
+	replication := &b.state.Replication
+	replication.Role = str(r.Values, "role")
+	replication.Rules = nil
+	for _, rule := range blocks(r.Values, "rule") {
+		ruleReport := audit.ReplicationRuleReport{
+			ID:      str(rule, "id"),
+			Enabled: str(rule, "status") == "Enabled",
+		}
+		if d := block(rule, "destination"); d != nil {
+			ruleReport.DestinationBucket = strings.TrimPrefix(str(d, "bucket"), "arn:aws:s3:::")
+			ruleReport.DestinationAccount = str(d, "account")
+			ruleReport.ReplicaKMSKeyID = str(block(d, "encryption_configuration"), "replica_kms_key_id")
+		}
+		ruleReport.DeleteMarkerReplication = str(block(rule, "delete_marker_replication"), "status") == "Enabled"
+		replication.Rules = append(replication.Rules, ruleReport)
+	}
+	b.state.SetResource(r.Address, audit.ControlReplication)
+}
+
+func applyWebsite(b *bucket, r resource, _ map[string]any) {
+	v := r.Values
+	w := &b.state.Website
+	*w = audit.WebsiteReport{Enabled: true, Endpoint: audit.WebsiteEndpoint(b.state.Name, b.state.Region)}
+	w.IndexDocument = str(block(v, "index_document"), "suffix")
+	w.ErrorDocument = str(block(v, "error_document"), "key")
+	if redirect := block(v, "redirect_all_requests_to"); redirect != nil {
+		w.RedirectAllTo = audit.RedirectTarget(str(redirect, "protocol"), str(redirect, "host_name"))
+	}
+	for _, rule := range blocks(v, "routing_rule") {
+		redirect := block(rule, "redirect")
+		if redirect == nil {
+			continue
+		}
+		condition := block(rule, "condition")
+		w.RedirectRules = append(w.RedirectRules, audit.RedirectRule(
+			optional(condition, "key_prefix_equals"), optional(condition, "http_error_code_returned_equals"),
+			str(redirect, "protocol"), optional(redirect, "host_name"),
+			optional(redirect, "replace_key_prefix_with"), optional(redirect, "replace_key_with")))
+	}
+	b.state.SetResource(r.Address, audit.ControlWebsite)
+}
+
+func applyCORS(b *bucket, r resource, _ map[string]any) {
+	b.state.CORS.Rules = nil
+	for _, rule := range blocks(r.Values, "cors_rule") {
+		b.state.CORS.Rules = append(b.state.CORS.Rules,
+			audit.NewCORSRuleReport(optional(rule, "id"), strs(rule, "allowed_origins"), strs(rule, "allowed_methods")))
+	}
+	b.state.SetResource(r.Address, audit.ControlCORS)
+}
+
+func applyNotification(b *bucket, r resource, _ map[string]any) {
+	notification := &b.state.Notification
+	notification.EventBridgeEnabled = boolean(r.Values, "eventbridge")
+	notification.Destinations = nil
+	for _, d := range []struct{ destinationType, block, arn string }{
+		{"sns", "topic", "topic_arn"},
+		{"sqs", "queue", "queue_arn"},
+		{"lambda", "lambda_function", "lambda_function_arn"},
+	} {
+		for _, c := range blocks(r.Values, d.block) {
+			notification.Destinations = append(notification.Destinations,
+				audit.NewNotificationDestination(d.destinationType, str(c, d.arn), strs(c, "events"), b.state.AccountID))
+		}
+	}
+	b.state.SetResource(r.Address, audit.ControlNotification)
+}
+
+// resolveDestinationRegions sets the region of replication destinations managed in the same plan.
+func (b *bucket) resolveDestinationRegions(byName map[string]*bucket) {
+	for i, rule := range b.state.Replication.Rules {
+		if destination, ok := byName[rule.DestinationBucket]; ok {
+			b.state.Replication.Rules[i].DestinationRegion = destination.state.Region
+		}
+	}
+}
+
+// finish applies the S3 defaults for configuration not managed in the plan and returns the state.
+func (b *bucket) finish() audit.BucketState {
+	if !b.encryptionSet {
+		b.state.ServerSideEncryptionEnabled = true
+		b.state.EncryptionAlgorithm = defaultEncryptionAlgorithm
+	}
+	if !b.bpaSet {
+		b.state.MarkUnavailable(bpaReason, audit.ControlBlockPublicAccess)
+	}
+	return b.state
+}
@@ -0,0 +1,242 @@
+// Package terraform reconstructs the S3 bucket configuration resulting from a Terraform plan or state
+// ('terraform show -json'), so buckets can be audited before they are deployed.
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/rollwagen/s3-cisbench/internal/audit"
+	log "github.com/sirupsen/logrus"
+)
+
+// resource is a resource of the planned values or the state, with its attribute values.
+type resource struct {
+	Address string         `json:"address"`
+	Mode    string         `json:"mode"`
+	Type    string         `json:"type"`
+	Values  map[string]any `json:"values"`
+}
+
+type module struct {
+	Resources    []resource `json:"resources"`
+	ChildModules []module   `json:"child_modules"`
+}
+
+type values struct {
+	RootModule module `json:"root_module"`
+}
+
+type expression struct {
+	ConstantValue any      `json:"constant_value"`
+	References    []string `json:"references"`
+}
+
+type configResource struct {
+	Address           string                `json:"address"`
+	ProviderConfigKey string                `json:"provider_config_key"`
+	Expressions       map[string]expression `json:"expressions"`
+}
+
+type providerConfig struct {
+	Name        string                `json:"name"`
+	Expressions map[string]expression `json:"expressions"`
+}
+
+type configModule struct {
+	Resources   []configResource `json:"resources"`
+	ModuleCalls map[string]struct {
+		Module configModule `json:"module"`
+	} `json:"module_calls"`
+}
+
+type resourceChange struct {
+	Address string `json:"address"`
+	Change  struct {
+		AfterUnknown map[string]any `json:"after_unknown"`
+	} `json:"change"`
+}
+
+// plan is the JSON representation of a plan or, with Values instead of PlannedValues, of a state.
+type plan struct {
+	PlannedValues   *values          `json:"planned_values"`
+	Values          *values          `json:"values"`
+	ResourceChanges []resourceChange `json:"resource_changes"`
+	Configuration   struct {
+		ProviderConfig map[string]providerConfig `json:"provider_config"`
+		RootModule     configModule              `json:"root_module"`
+	} `json:"configuration"`
+}
+
+var instanceKey = regexp.MustCompile(`\[[^\]]*\]`)
+
+// configAddress strips the instance keys of count and for_each from a resource address.
+func configAddress(address string) string {
+	return instanceKey.ReplaceAllString(address, "")
+}
+
+// instanceKeys returns the instance keys of an address, e.g. '[0]["a"]'.
+func instanceKeys(address string) string {
+	return strings.Join(instanceKey.FindAllString(address, -1), "")
+}
+
+func (m module) resources() []resource {
+	resources := m.Resources
+	for _, child := range m.ChildModules {
+		resources = append(resources, child.resources()...)
+	}
+	return resources
+}
+
+// addConfigResources adds the configuration of all resources of the module and its module calls by address.
+func (m configModule) addConfigResources(prefix string, configs map[string]configResource) {
+	for _, r := range m.Resources {
+		configs[prefix+r.Address] = r
+	}
+	for name, call := range m.ModuleCalls {
+		call.Module.addConfigResources(prefix+"module."+name+".", configs)
+	}
+}
+
+// provider returns the configuration of the AWS provider a resource is managed with, e.g. of the alias
+// 'aws.replica'; if the resource's provider is not known, that of the default, unaliased provider.
+func (p *plan) provider(config configResource) providerConfig {
+	if provider, ok := p.Configuration.ProviderConfig[config.ProviderConfigKey]; ok && provider.Name == "aws" {
+		return provider
+	}
+	return p.Configuration.ProviderConfig["aws"]
+}
+
+// str returns the constant value of an argument of the provider; of a list, the first element.
+func (c providerConfig) str(argument string) string {
+	switch v := c.Expressions[argument].ConstantValue.(type) {
+	case string:
+		return v
+	case []any:
+		if len(v) != 0 {
+			s, _ := v[0].(string)
+			return s
+		}
+	}
+	return ""
+}
+
+// LoadPlan reads the output of 'terraform show -json' for a plan or state and returns one bucket state per
+// aws_s3_bucket with the resulting configuration; the controls still need to be evaluated. The state's
+// Resource and the findings' resources refer to the Terraform resource addresses. The region, and if
+// accountID is empty the account from 'allowed_account_ids', is taken from the bucket's provider.
+func LoadPlan(path string, accountID string) ([]audit.BucketState, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p plan
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", path, err)
+	}
+
+	v := p.PlannedValues
+	if v == nil {
+		v = p.Values
+	}
+	if v == nil {
+		return nil, fmt.Errorf("%s is neither a Terraform plan nor state in JSON format ('terraform show -json')", path)
+	}
+
+	configs := map[string]configResource{}
+	p.Configuration.RootModule.addConfigResources("", configs)
+	unknown := map[string]map[string]any{}
+	for _, change := range p.ResourceChanges {
+		unknown[change.Address] = change.Change.AfterUnknown
+	}
+
+	var buckets []*bucket
+	var others []resource
+	for _, r := range v.RootModule.resources() {
+		if r.Mode != "" && r.Mode != "managed" {
+			continue
+		}
+		if r.Type == "aws_s3_bucket" {
+			provider := p.provider(configs[configAddress(r.Address)])
+			account := accountID
+			if account == "" {
+				account = provider.str("allowed_account_ids")
+			}
+			buckets = append(buckets, newBucket(r, unknown[r.Address], account, provider.str("region")))
+		} else if _, ok := bucketResources[r.Type]; ok {
+			others = append(others, r)
+		}
+	}
+
+	for _, r := range others {
+		target := findBucket(buckets, r, configs)
+		if target == nil {
+			log.Warnf("Skipping %s, its bucket is not managed in this plan", r.Address)
+			continue
+		}
+		log.Debugf("Applying %s to bucket %s", r.Address, target.resource.Address)
+		bucketResources[r.Type](target, r, unknown[r.Address])
+	}
+
+	byName := map[string]*bucket{}
+	for _, b := range buckets {
+		byName[b.state.Name] = b
+	}
+	var states []audit.BucketState
+	for _, b := range buckets {
+		b.resolveDestinationRegions(byName)
+		states = append(states, b.finish())
+	}
+	return states, nil
+}
+
+// findBucket returns the bucket a resource like aws_s3_bucket_versioning applies to, by the bucket
+// name if known or else by the reference to the aws_s3_bucket in the configuration.
+func findBucket(buckets []*bucket, r resource, configs map[string]configResource) *bucket {
+	if name, ok := r.Values["bucket"].(string); ok && name != "" {
+		for _, b := range buckets {
+			if b.state.Name == name {
+				return b
+			}
+		}
+	}
+
+	// references are relative to the module of the resource, e.g. 'module.a.' for 'module.a.<type>.<name>'
+	address := configAddress(r.Address)
+	var modulePrefix string
+	if segments := strings.Split(address, "."); len(segments) > 2 {
+		modulePrefix = strings.Join(segments[:len(segments)-2], ".") + "."
+	}
+
+	config, ok := configs[address]
+	if !ok {
+		return nil
+	}
+	for _, reference := range config.Expressions["bucket"].References {
+		if !strings.HasPrefix(reference, "aws_s3_bucket.") {
+			continue
+		}
+		parts := strings.Split(reference, ".")
+		target := modulePrefix + parts[0] + "." + configAddress(parts[1])
+
+		var candidates []*bucket
+		for _, b := range buckets {
+			if configAddress(b.resource.Address) == target {
+				candidates = append(candidates, b)
+			}
+		}
+		for _, b := range candidates {
+			if instanceKeys(b.resource.Address) == instanceKeys(r.Address) {
+				return b
+			}
+		}
+		// a bucket without count or for_each is unambiguous, otherwise the instance is not known
+		if len(candidates) == 1 && instanceKeys(candidates[0].resource.Address) == "" {
+			return candidates[0]
+		}
+	}
+	return nil
+}